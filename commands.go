@@ -0,0 +1,565 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/textui"
+)
+
+// PUZZLE_FILE is the puzzle file shipped with the game.
+const PUZZLE_FILE = "res/data/puzzles.json"
+
+// requirePositive stops with an error if the flag called name is below 1.
+func requirePositive(name string, v int) {
+	if v < 1 {
+		log.Fatalf("-%s must be at least 1, not %d", name, v)
+	}
+}
+
+func agentTest(args []string) {
+	iterations := 10
+	if len(args) > 0 {
+		var err error
+		iterations, err = strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Println("unable to parse iterations, defaulting to 10")
+		}
+	}
+	// an optional weights file puts a model agent in the first seat
+	var model *core.LinearModel
+	if len(args) > 1 {
+		var err error
+		model, err = core.LoadLinearModel(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	p1Wins := 0
+	p2Wins := 0
+	p1TotalScore := 0
+	p2TotalScore := 0
+	draws := 0
+	for i := range iterations {
+		game := core.NewGame()
+		var a1 core.GameAgent = core.NewSampleAgent(0)
+		if model != nil {
+			a1 = core.NewModelAgent(0, model)
+		}
+		a2 := core.NewSampleAgent(1)
+		a2.Strategy = 1
+		if err := core.RunGame(game, [2]core.GameAgent{a1, a2}); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Game %d\n", i)
+		if game.State == core.P1_WIN {
+			fmt.Println("p1 win")
+			p1Wins += 1
+		} else if game.State == core.P2_WIN {
+			fmt.Println("p2 win")
+			p2Wins += 1
+		} else {
+			fmt.Println("draw")
+			draws += 1
+		}
+		p1TotalScore += game.Pyramid1.Score()
+		p2TotalScore += game.Pyramid2.Score()
+		fmt.Printf("Score %d - %d\n", game.Pyramid1.Score(), game.Pyramid2.Score())
+	}
+	fmt.Printf("Results %d %d %d\n", p1Wins, p2Wins, draws)
+	fmt.Printf("Avg scores %.2f %.2f\n", float64(p1TotalScore)/float64(iterations), float64(p2TotalScore)/float64(iterations))
+}
+
+func train(args []string) {
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	generations := fs.Int("generations", 5, "number of self-play generations")
+	games := fs.Int("games", 500, "self-play games per generation")
+	seed := fs.Int64("seed", 1, "seed for deals and exploration")
+	epsilon := fs.Float64("epsilon", 0.05, "chance of a random move during self-play")
+	ridge := fs.Float64("ridge", 1, "regularization applied to the fit")
+	in := fs.String("in", "", "weights file to start from, the built in weights if empty")
+	out := fs.String("out", "weights.json", "file the trained weights are written to")
+	fs.Parse(args)
+	requirePositive("generations", *generations)
+	requirePositive("games", *games)
+
+	start := core.DefaultModel
+	if *in != "" {
+		var err error
+		start, err = core.LoadLinearModel(*in)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	config := core.TrainConfig{
+		Generations: *generations,
+		Games:       *games,
+		Seed:        *seed,
+		Epsilon:     *epsilon,
+		Ridge:       *ridge,
+	}
+	model, err := core.Train(start, config, func(r core.GenerationResult) {
+		fmt.Printf("Generation %d: %d samples, mse %.2f\n", r.Generation, r.Samples, r.MSE)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := model.Save(*out); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Wrote weights to " + *out)
+}
+
+func dataset(args []string) {
+	fs := flag.NewFlagSet("dataset", flag.ExitOnError)
+	games := fs.Int("games", 100, "number of games to play")
+	seed := fs.Int64("seed", 1, "seed of the first game, later games count up from it")
+	p1 := fs.String("p1", "sample", "agent in the first seat")
+	p2 := fs.String("p2", "model", "agent in the second seat")
+	variant := fs.String("variant", "standard", "rules variant")
+	weights := fs.String("weights", "", "weights file for model agents and estimates")
+	format := fs.String("format", "jsonl", "output format, jsonl or csv")
+	out := fs.String("out", "", "output file, stdout if empty")
+	fs.Parse(args)
+	requirePositive("games", *games)
+
+	rules, err := core.RulesByName(*variant)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config := core.DatasetConfig{
+		Games:  *games,
+		Seed:   *seed,
+		Agents: [2]string{*p1, *p2},
+		Rules:  rules,
+	}
+	if *weights != "" {
+		var err error
+		config.Model, err = core.LoadLinearModel(*weights)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	f := os.Stdout
+	if *out != "" {
+		var err error
+		f, err = os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+	}
+	w := bufio.NewWriter(f)
+
+	switch *format {
+	case "jsonl":
+		err = core.GenerateDataset(config, func(rec core.DecisionRecord) error {
+			return core.WriteJSONLRecord(w, rec)
+		})
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(core.DatasetCSVHeader()); err != nil {
+			log.Fatal(err)
+		}
+		err = core.GenerateDataset(config, func(rec core.DecisionRecord) error {
+			return core.WriteCSVRecord(cw, rec)
+		})
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+	default:
+		log.Fatal("unknown format " + *format)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func simulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	p1 := fs.String("p1", "sample", "agent in the first seat, one of "+strings.Join(core.AgentNames, ", "))
+	p2 := fs.String("p2", "sample", "agent in the second seat")
+	seed := fs.Int64("seed", 1, "seed of the first game, later games count up from it")
+	games := fs.Int("games", 100, "number of games to play")
+	workers := fs.Int("workers", runtime.NumCPU(), "games played in parallel")
+	variant := fs.String("variant", "standard", "rules variant, one of "+strings.Join(core.VariantNames(), ", "))
+	weights := fs.String("weights", "", "weights file for model agents")
+	luck := fs.Bool("luck", false, "measure luck and skill for every game")
+	format := fs.String("format", "json", "output format, json or csv")
+	out := fs.String("out", "", "output file, stdout if empty")
+	fs.Parse(args)
+	requirePositive("games", *games)
+
+	rules, err := core.RulesByName(*variant)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config := core.SimConfig{
+		Games:    *games,
+		Seed:     *seed,
+		Agents:   [2]string{*p1, *p2},
+		Rules:    rules,
+		Workers:  *workers,
+		WithLuck: *luck,
+	}
+	if *weights != "" {
+		config.Model, err = core.LoadLinearModel(*weights)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.LuckModel = config.Model
+	}
+
+	results, err := core.Simulate(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	summary := core.Summarize(results)
+
+	f := os.Stdout
+	if *out != "" {
+		f, err = os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+	}
+	w := bufio.NewWriter(f)
+
+	switch *format {
+	case "json":
+		doc := struct {
+			Agents  [2]string         `json:"agents"`
+			Variant string            `json:"variant"`
+			Seed    int64             `json:"seed"`
+			Summary core.SimSummary   `json:"summary"`
+			Games   []core.GameResult `json:"games"`
+		}{config.Agents, rules.Name, *seed, summary, results}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(doc)
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := []string{"game", "seed", "p1_agent", "p2_agent", "variant", "p1_score", "p2_score", "outcome", "moves"}
+		if *luck {
+			header = append(header, "p1_best", "p2_best", "p1_par", "p2_par", "p1_luck", "p1_skill", "p2_skill")
+		}
+		err = cw.Write(header)
+		for _, r := range results {
+			if err != nil {
+				break
+			}
+			row := []string{
+				strconv.Itoa(r.Game),
+				strconv.FormatInt(r.Seed, 10),
+				r.Agents[0],
+				r.Agents[1],
+				r.Variant,
+				strconv.Itoa(r.Scores[0]),
+				strconv.Itoa(r.Scores[1]),
+				r.Outcome,
+				strconv.Itoa(r.Moves),
+			}
+			if r.Luck != nil {
+				for _, v := range []int{r.Luck.Best[0], r.Luck.Best[1], r.Luck.Par[0], r.Luck.Par[1], r.Luck.Luck[0], r.Luck.Skill[0], r.Luck.Skill[1]} {
+					row = append(row, strconv.Itoa(v))
+				}
+			}
+			err = cw.Write(row)
+		}
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+	default:
+		log.Fatal("unknown format " + *format)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Results %d %d %d\n", summary.Wins[0], summary.Wins[1], summary.Draws)
+	fmt.Fprintf(os.Stderr, "Avg scores %.2f %.2f\n", summary.AvgScore[0], summary.AvgScore[1])
+}
+
+func play(args []string) {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	seats := "human, " + strings.Join(core.AgentNames, ", ")
+	p1 := fs.String("p1", "human", "player in the first seat, one of "+seats)
+	p2 := fs.String("p2", "model", "player in the second seat, one of "+seats)
+	seed := fs.Int64("seed", 0, "seed of the deal, random if 0")
+	variant := fs.String("variant", "standard", "rules variant, one of "+strings.Join(core.VariantNames(), ", "))
+	weights := fs.String("weights", "", "weights file for model agents and hints")
+	save := fs.String("save", "", "archive file the finished game is added to")
+	position := fs.String("position", "", "position string to start from instead of a new deal")
+	daily := fs.Bool("daily", false, "play today's daily challenge, which sets the deal and the opponent")
+	solo := fs.Bool("solo", false, "play a solo game against the target score")
+	fs.Parse(args)
+
+	if *daily {
+		playDaily(*save)
+		return
+	}
+
+	rules, err := core.RulesByName(*variant)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var model *core.LinearModel
+	if *weights != "" {
+		model, err = core.LoadLinearModel(*weights)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	if *solo {
+		playSolo(*seed, rules, model, *save)
+		return
+	}
+	game := core.NewVariantGame(*seed, rules)
+	if *position != "" {
+		game, err = core.NewGameFromPosition(*position, *seed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		rules = game.Rules
+	}
+	var agents [2]core.GameAgent
+	for p, name := range []string{*p1, *p2} {
+		if name == "human" {
+			continue
+		}
+		agents[p], err = core.NewAgent(name, p, *seed*2+int64(p), rules)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if ma, ok := agents[p].(*core.ModelAgent); ok && model != nil {
+			ma.Model = model
+		}
+	}
+	core.SetPosition(game, agents)
+	fmt.Printf("Seed %d, %s rules\n", *seed, rules.Name)
+	err = textui.Play(os.Stdin, os.Stdout, game, agents, model)
+	if err != nil && err != textui.ErrQuit {
+		log.Fatal(err)
+	}
+	if code, err := core.ShareCode(game.Record()); err == nil {
+		fmt.Println("Game code: " + code)
+	}
+	if *save != "" {
+		saveArchivedGame(*save, core.NewArchivedGame(game, [2]string{"Player 1", "Player 2"}, [2]string{*p1, *p2}))
+	}
+}
+
+func playDaily(save string) {
+	date := core.DailyDate(time.Now())
+	game, agent, err := core.NewDailyGame(date)
+	if err != nil {
+		log.Fatal(err)
+	}
+	history, err := core.LoadDailyHistory()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if r, ok := history.Result(date); ok {
+		fmt.Printf("You already played today's challenge, %d to %d. This game won't count.\n", r.Score, r.OppScore)
+	}
+	fmt.Printf("Daily challenge %s against the %s agent\n", date, core.DAILY_AGENT)
+	err = textui.Play(os.Stdin, os.Stdout, game, [2]core.GameAgent{nil, agent}, nil)
+	if err == textui.ErrQuit {
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+	if history.Add(core.NewDailyResult(date, game)) {
+		if err := history.Save(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Printf("Streak: %d days, %d wins in a row, best %d days\n", history.Streak(date), history.WinStreak(date), history.BestStreak())
+	if save != "" {
+		saveArchivedGame(save, core.NewArchivedGame(game, [2]string{"Player 1", core.DAILY_AGENT}, [2]string{"human", core.DAILY_AGENT}))
+	}
+}
+
+func playSolo(seed int64, rules core.Rules, model *core.LinearModel, save string) {
+	game := core.NewSoloGame(seed, rules)
+	stats, err := core.LoadSoloStats()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Solo game, seed %d, %s rules. Target %d, your best %d\n", seed, rules.Name, core.SOLO_TARGET, stats.BestScore)
+	err = textui.Play(os.Stdin, os.Stdout, game, [2]core.GameAgent{}, model)
+	if err == textui.ErrQuit {
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+	rating, err := core.RateSoloGame(game.Record())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if stats.Add(rating) {
+		fmt.Println("New personal best!")
+	}
+	if err := stats.Save(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Best possible %d, rating %.0f%%\n", rating.Best, rating.Percent)
+	if code, err := core.ShareCode(game.Record()); err == nil {
+		fmt.Println("Game code: " + code)
+	}
+	if save != "" {
+		saveArchivedGame(save, core.NewArchivedGame(game, [2]string{"Player 1", ""}, [2]string{"human", ""}))
+	}
+}
+
+// saveArchivedGame appends the game to an archive file, creating it if needed.
+func saveArchivedGame(path string, a *core.ArchivedGame) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		f.WriteString("\n")
+	}
+	if _, err := f.WriteString(a.String()); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Saved to " + path)
+}
+
+// puzzles adds newly generated puzzles to the puzzle file, or with -check
+// solves the puzzles already in it again. With -position it solves a single
+// position, such as one built in the sandbox, and adds it if an action is
+// clearly best.
+func puzzles(args []string) {
+	fs := flag.NewFlagSet("puzzles", flag.ExitOnError)
+	seed := fs.Int64("seed", 1, "seed of the first game, later games count up from it")
+	games := fs.Int("games", 50, "number of games to look for puzzles in")
+	samples := fs.Int("samples", 200, "play outs for each action")
+	workers := fs.Int("workers", runtime.NumCPU(), "games searched in parallel")
+	variant := fs.String("variant", "standard", "rules variant, one of "+strings.Join(core.VariantNames(), ", "))
+	weights := fs.String("weights", "", "weights file for the agents playing out positions")
+	out := fs.String("out", PUZZLE_FILE, "puzzle file to add to")
+	limit := fs.Int("max", 0, "most puzzles of each difficulty to keep in the file, 0 for no limit")
+	difficulty := fs.String("difficulty", "", "only look for puzzles of this difficulty, one of "+strings.Join(core.PuzzleDifficulties, ", "))
+	check := fs.Bool("check", false, "solve the puzzles in the file again instead of generating")
+	position := fs.String("position", "", "solve this position and add it instead of generating")
+	fs.Parse(args)
+	requirePositive("games", *games)
+
+	rules, err := core.RulesByName(*variant)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *difficulty != "" && !slices.Contains(core.PuzzleDifficulties, *difficulty) {
+		log.Fatal("unknown difficulty " + *difficulty)
+	}
+	model := core.DefaultModel
+	if *weights != "" {
+		model, err = core.LoadLinearModel(*weights)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	existing, err := core.LoadPuzzles(*out)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+
+	if *check {
+		failed := 0
+		for _, p := range existing {
+			if err := core.CheckPuzzle(p, *samples, *seed, model); err != nil {
+				fmt.Printf("%s: %v\n", p.ID, err)
+				failed += 1
+			}
+		}
+		fmt.Printf("%d of %d puzzles hold\n", len(existing)-failed, len(existing))
+		return
+	}
+
+	var found []*core.Puzzle
+	if *position != "" {
+		s, err := core.SolvePosition(*position, *samples, *seed, model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, v := range s.Values {
+			fmt.Printf("%-7s %6.2f\n", v.Action, v.Value)
+		}
+		gap, stderr := s.Gap()
+		fmt.Printf("gap %.2f, standard error %.2f\n", gap, stderr)
+		if !s.Clear() {
+			fmt.Println("No action is clearly best, not adding it")
+			return
+		}
+		p, err := core.NewPuzzleFromSolution(*position, s, *samples, *seed, model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if p == nil {
+			fmt.Println("The answer doesn't hold on a second set of shuffles, not adding it")
+			return
+		}
+		found = append(found, p)
+	} else {
+		found, err = core.GeneratePuzzles(core.PuzzleConfig{
+			Seed:       *seed,
+			Games:      *games,
+			Samples:    *samples,
+			Rules:      rules,
+			Model:      model,
+			Workers:    *workers,
+			Difficulty: *difficulty,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	known := map[string]bool{}
+	counts := map[string]int{}
+	for _, p := range existing {
+		known[p.ID] = true
+		counts[p.Difficulty] += 1
+	}
+	added := 0
+	for _, p := range found {
+		if !known[p.ID] && (*limit == 0 || counts[p.Difficulty] < *limit) {
+			existing = append(existing, p)
+			known[p.ID] = true
+			counts[p.Difficulty] += 1
+			added += 1
+		}
+	}
+	if err := core.SavePuzzles(*out, existing); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Added %d puzzles to %s, now %d easy, %d medium, %d hard\n", added, *out,
+		counts[core.PUZZLE_EASY], counts[core.PUZZLE_MEDIUM], counts[core.PUZZLE_HARD])
+}
@@ -1,7 +1,10 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"math/rand"
+	"strconv"
 )
 
 type EventType int
@@ -16,6 +19,13 @@ type AgentEvent struct {
 	Target    int
 }
 
+func (e AgentEvent) String() string {
+	if e.EventType == DRAW_CARDS {
+		return "draw"
+	}
+	return "play " + strconv.Itoa(e.Target)
+}
+
 type GameAgent interface {
 	GenerateMove() AgentEvent
 	AcceptMove(card *Card, index int)
@@ -23,6 +33,93 @@ type GameAgent interface {
 	SetVisibleCard(card *Card)
 }
 
+// ActionEvaluator is implemented by agents that can score the legal actions of
+// the position they are about to move in.
+type ActionEvaluator interface {
+	ActionValues() []ActionValue
+}
+
+// CancellableAgent is implemented by agents whose search takes long enough to
+// be worth stopping. GenerateMoveContext gives up early once ctx is done, and
+// the move it returns then must not be played.
+type CancellableAgent interface {
+	GenerateMoveContext(ctx context.Context) AgentEvent
+}
+
+// PositionAgent is implemented by agents that can join a game set up from a
+// position rather than following it from the deal.
+type PositionAgent interface {
+	SetPosition(g *Game)
+}
+
+// SetPosition tells every agent that supports it about the current position
+// of g. Seats without an agent are skipped.
+func SetPosition(g *Game, agents [2]GameAgent) {
+	for _, a := range agents {
+		if pa, ok := a.(PositionAgent); ok {
+			pa.SetPosition(g)
+		}
+	}
+}
+
+// publicPyramids copies both pyramids of g so an agent can fill them in.
+func publicPyramids(g *Game) [2]*Pyramid {
+	return [2]*Pyramid{&Pyramid{Cards: g.Pyramid1.Cards}, &Pyramid{Cards: g.Pyramid2.Cards}}
+}
+
+// drawsLeftFor is how many draws the player has left, which is a full turn's
+// worth while the opponent is to move.
+func drawsLeftFor(g *Game, playerNumber, drawsPerTurn int) int {
+	if g.CurrentPlayer() == playerNumber {
+		return g.DrawsLeft
+	}
+	return drawsPerTurn
+}
+
+// seenInPosition marks every card that is face up in g.
+func seenInPosition(g *Game) [40]bool {
+	var seen [40]bool
+	for _, cards := range [][]*Card{g.Pyramid1.Cards[:], g.Pyramid2.Cards[:], g.Discards} {
+		for _, c := range cards {
+			if c != nil {
+				seen[CardToIndex(c)] = true
+			}
+		}
+	}
+	return seen
+}
+
+var AgentNames = []string{"random", "sample", "model"}
+
+// NewAgent builds an agent by name for a game played with rules. The seed
+// makes its choices repeatable.
+func NewAgent(name string, playerNumber int, seed int64, rules Rules) (GameAgent, error) {
+	r := rand.New(rand.NewSource(seed))
+	switch name {
+	case "random":
+		a := NewRandomAgent(playerNumber)
+		a.Rand = r
+		a.DrawsPerTurn = rules.DrawsPerTurn
+		a.ViewsRemaining = rules.DrawsPerTurn
+		return a, nil
+	case "sample":
+		a := NewSampleAgent(playerNumber)
+		a.Rand = r
+		a.ValuesSeed = valuesSeed(seed)
+		a.Orientation = r.Intn(6)
+		a.DrawsPerTurn = rules.DrawsPerTurn
+		a.DrawsRemaining = rules.DrawsPerTurn
+		return a, nil
+	case "model":
+		a := NewModelAgent(playerNumber, nil)
+		a.Rand = r
+		a.DrawsPerTurn = rules.DrawsPerTurn
+		a.DrawsRemaining = rules.DrawsPerTurn
+		return a, nil
+	}
+	return nil, errors.New("unknown agent " + name)
+}
+
 func CardToIndex(c *Card) int {
 	return c.Color*20 + c.Copy*10 + c.Value - 1
 }
@@ -44,6 +141,7 @@ func NewShuffledDeck(r *rand.Rand) [40]*Card {
 
 type RandomAgent struct {
 	PlayerNumber   int
+	DrawsPerTurn   int
 	Rand           *rand.Rand
 	ViewsRemaining int
 	VisibleCard    *Card
@@ -56,6 +154,7 @@ func NewRandomAgent(playerNumber int) *RandomAgent {
 		PlayerNumber:   playerNumber,
 		Pyramids:       [2]*Pyramid{&Pyramid{}, &Pyramid{}},
 		ViewsRemaining: 2,
+		DrawsPerTurn:   2,
 	}
 }
 
@@ -87,7 +186,7 @@ func (a *RandomAgent) GenerateMove() AgentEvent {
 	a.Pyramids[a.PlayerNumber].Cards[target] = a.VisibleCard
 	//fmt.Printf("Agent %d: Playing card %s at %d\n", a.PlayerNumber+1, a.VisibleCard.String(), target)
 	a.VisibleCard = nil
-	a.ViewsRemaining = 2
+	a.ViewsRemaining = a.DrawsPerTurn
 	return AgentEvent{
 		EventType: PLAY_CARD,
 		Target:    target,
@@ -111,7 +210,7 @@ func (a *RandomAgent) ChooseSlot() int {
 
 func (a *RandomAgent) AcceptMove(card *Card, index int) {
 	a.Pyramids[1-a.PlayerNumber].Cards[index] = card
-	a.ViewsRemaining = 2
+	a.ViewsRemaining = a.DrawsPerTurn
 }
 
 func (a *RandomAgent) RevealCard(card *Card) {
@@ -119,27 +218,43 @@ func (a *RandomAgent) RevealCard(card *Card) {
 	a.ViewsRemaining -= 1
 }
 
+func (a *RandomAgent) SetPosition(g *Game) {
+	a.Pyramids = publicPyramids(g)
+	a.VisibleCard = g.TopDiscard()
+	a.ViewsRemaining = drawsLeftFor(g, a.PlayerNumber, a.DrawsPerTurn)
+}
+
 type SampleAgent struct {
 	Orientation    int
 	PlayerNumber   int
 	Rand           *rand.Rand
+	DrawsPerTurn   int
 	DrawsRemaining int
 	CardsPlayed    int
 	VisibleCard    *Card
 	Pyramids       [2]*Pyramid
 	SeenCards      [40]bool
+	ValuesSeed     int64 // seeds the completions ActionValues samples
 
 	Strategy int
 }
 
+// valuesSeed is the first number a generator seeded with seed gives, drawn
+// from a copy of it so the agent's own moves don't change.
+func valuesSeed(seed int64) int64 {
+	return rand.New(rand.NewSource(seed)).Int63()
+}
+
 func NewSampleAgent(playerNumber int) *SampleAgent {
 	r := rand.New(rand.NewSource(0))
 	orientation := rand.Intn(6)
 	return &SampleAgent{
 		Rand:           r,
+		ValuesSeed:     valuesSeed(0),
 		Orientation:    orientation,
 		PlayerNumber:   playerNumber,
 		Pyramids:       [2]*Pyramid{&Pyramid{}, &Pyramid{}},
+		DrawsPerTurn:   2,
 		DrawsRemaining: 2,
 	}
 }
@@ -148,7 +263,7 @@ func (a *SampleAgent) RecordMove(target int) AgentEvent {
 	//fmt.Printf("Agent %d: Playing card "+a.VisibleCard.String()+" at target %d\n", a.PlayerNumber+1, target)
 	a.Pyramids[a.PlayerNumber].Cards[target] = a.VisibleCard
 	a.VisibleCard = nil
-	a.DrawsRemaining = 2
+	a.DrawsRemaining = a.DrawsPerTurn
 	a.CardsPlayed += 1
 	return AgentEvent{
 		EventType: PLAY_CARD,
@@ -163,23 +278,35 @@ func (a *SampleAgent) RecordDraw() AgentEvent {
 	}
 }
 
+// AvailableSlots are the slots the agent considers, those its opening plan
+// allows that can still be filled. If none of them can, which happens when the
+// agent joins a game it didn't open, every open slot is considered.
 func (a *SampleAgent) AvailableSlots() []int {
-	if a.CardsPlayed == 0 {
-		switch a.Orientation {
-		case 0:
-			return []int{0, 1}
-		case 1:
-			return []int{0, 2}
-		case 2:
-			return []int{3, 1}
-		case 3:
-			return []int{3, 4}
-		case 4:
-			return []int{5, 2}
-		case 5:
-		default:
-			return []int{5, 4}
+	own := a.Pyramids[a.PlayerNumber]
+	slots := []int{}
+	for _, i := range a.plannedSlots() {
+		if own.CanPlace(i) {
+			slots = append(slots, i)
+		}
+	}
+	if len(slots) > 0 {
+		return slots
+	}
+	for i := range 10 {
+		if own.CanPlace(i) {
+			slots = append(slots, i)
 		}
+	}
+	return slots
+}
+
+// SAMPLE_OPENINGS are the two corners or edges each orientation fills first.
+var SAMPLE_OPENINGS = [6][2]int{{0, 1}, {0, 2}, {3, 1}, {3, 4}, {5, 2}, {5, 4}}
+
+func (a *SampleAgent) plannedSlots() []int {
+	if a.CardsPlayed == 0 {
+		opening := SAMPLE_OPENINGS[a.Orientation]
+		return []int{opening[0], opening[1]}
 	} else if a.CardsPlayed == 1 {
 		switch a.Orientation {
 		case 0:
@@ -244,13 +371,10 @@ func (a *SampleAgent) RandomUnseenIndexAndCard() (int, *Card) {
 	return randIndex, IndexToCard(randIndex)
 }
 
-func (a *SampleAgent) GenerateMoveB(iterations, drawIterations int) AgentEvent {
-	if a.VisibleCard == nil {
-		return a.RecordDraw()
-	}
+// sampleCompletions fills the empty slots of the agent's pyramid with unseen
+// cards, once per iteration.
+func (a *SampleAgent) sampleCompletions(iterations int) [][10]*Card {
 	p := a.Pyramids[a.PlayerNumber]
-	slots := a.AvailableSlots()
-
 	emptySlots := []int{}
 	for i := range 10 {
 		if p.Cards[i] == nil {
@@ -271,6 +395,81 @@ func (a *SampleAgent) GenerateMoveB(iterations, drawIterations int) AgentEvent {
 		}
 		//fmt.Println(samples[i])
 	}
+	return samples
+}
+
+// ActionValues estimates the final pyramid score of each legal action from
+// sampled completions, the same way GenerateMoveB compares them.
+func (a *SampleAgent) ActionValues() []ActionValue {
+	const iterations, drawIterations = 100, 20
+	// use a separate generator so asking for values does not change the moves
+	// the agent goes on to make, seeded by the agent so other games sample
+	// other completions
+	r := a.Rand
+	a.Rand = rand.New(rand.NewSource(a.ValuesSeed + int64(a.CardsPlayed*3+a.DrawsRemaining)))
+	defer func() { a.Rand = r }()
+
+	values := []ActionValue{}
+	samples := a.sampleCompletions(iterations)
+	tempPyramid := &Pyramid{}
+	if a.DrawsRemaining > 0 {
+		total := 0
+		for i := range iterations {
+			tempPyramid.Cards = samples[i]
+			for range drawIterations {
+				_, randCard := a.RandomUnseenIndexAndCard()
+				best := 0
+				for slot := range 10 {
+					if a.Pyramids[a.PlayerNumber].CanPlace(slot) {
+						best = max(best, tempPyramid.TentativeScoreWithCard(randCard, slot))
+					}
+				}
+				total += best
+			}
+		}
+		values = append(values, ActionValue{
+			Event: AgentEvent{EventType: DRAW_CARDS},
+			Value: float64(total) / float64(iterations*drawIterations),
+		})
+	}
+	if a.VisibleCard == nil {
+		return values
+	}
+	for slot := range 10 {
+		if !a.Pyramids[a.PlayerNumber].CanPlace(slot) {
+			continue
+		}
+		total := 0
+		for i := range iterations {
+			tempPyramid.Cards = samples[i]
+			total += tempPyramid.TentativeScoreWithCard(a.VisibleCard, slot)
+		}
+		values = append(values, ActionValue{
+			Event: AgentEvent{EventType: PLAY_CARD, Target: slot},
+			Value: float64(total) / float64(iterations),
+		})
+	}
+	return values
+}
+
+func (a *SampleAgent) GenerateMoveContext(ctx context.Context) AgentEvent {
+	return a.generateMove(ctx, 100, 20)
+}
+
+func (a *SampleAgent) GenerateMoveB(iterations, drawIterations int) AgentEvent {
+	return a.generateMove(context.Background(), iterations, drawIterations)
+}
+
+// generateMove samples completions of the agent's pyramid to choose between
+// the open card and a draw. It stops sampling once ctx is done, returning a
+// draw it has not recorded.
+func (a *SampleAgent) generateMove(ctx context.Context, iterations, drawIterations int) AgentEvent {
+	if a.VisibleCard == nil {
+		return a.RecordDraw()
+	}
+	slots := a.AvailableSlots()
+
+	samples := a.sampleCompletions(iterations)
 
 	slotScores := make([][]int, iterations) //[iterations][]int{}
 	for i := range iterations {
@@ -278,6 +477,9 @@ func (a *SampleAgent) GenerateMoveB(iterations, drawIterations int) AgentEvent {
 	}
 	tempPyrmaid := &Pyramid{}
 	for sI, slot := range slots {
+		if ctx.Err() != nil {
+			return AgentEvent{EventType: DRAW_CARDS}
+		}
 		for i := range iterations {
 			tempPyrmaid.Cards = samples[i]
 			score := tempPyrmaid.TentativeScoreWithCard(a.VisibleCard, slot)
@@ -312,6 +514,9 @@ func (a *SampleAgent) GenerateMoveB(iterations, drawIterations int) AgentEvent {
 
 	pendScores := make([]int, iterations)
 	for i := range iterations {
+		if ctx.Err() != nil {
+			return AgentEvent{EventType: DRAW_CARDS}
+		}
 		iterScore := 0
 		tempPyrmaid.Cards = samples[i]
 		for range drawIterations {
@@ -339,7 +544,7 @@ func (a *SampleAgent) GenerateMoveB(iterations, drawIterations int) AgentEvent {
 
 func (a *SampleAgent) AcceptMove(card *Card, index int) {
 	a.Pyramids[1-a.PlayerNumber].Cards[index] = card
-	a.DrawsRemaining = 2
+	a.DrawsRemaining = a.DrawsPerTurn
 }
 
 func (a *SampleAgent) RevealCard(card *Card) {
@@ -350,3 +555,21 @@ func (a *SampleAgent) RevealCard(card *Card) {
 func (a *SampleAgent) SetVisibleCard(c *Card) {
 	a.VisibleCard = c
 }
+
+func (a *SampleAgent) SetPosition(g *Game) {
+	a.Pyramids = publicPyramids(g)
+	a.SeenCards = seenInPosition(g)
+	a.CardsPlayed = a.Pyramids[a.PlayerNumber].Count()
+	if a.CardsPlayed == 1 {
+		// keep to an orientation that opens with the card already placed
+		own := a.Pyramids[a.PlayerNumber]
+		for o, opening := range SAMPLE_OPENINGS {
+			if own.Cards[opening[0]] != nil || own.Cards[opening[1]] != nil {
+				a.Orientation = o
+				break
+			}
+		}
+	}
+	a.VisibleCard = g.TopDiscard()
+	a.DrawsRemaining = drawsLeftFor(g, a.PlayerNumber, a.DrawsPerTurn)
+}
@@ -0,0 +1,49 @@
+package core
+
+// BLUNDER_LOSS is how much expected margin a move has to give up before the
+// analysis flags it as a blunder.
+const BLUNDER_LOSS = 3.0
+
+type MoveAnalysis struct {
+	Move    Move
+	Values  []ActionValue
+	Best    ActionValue
+	Chosen  float64
+	Loss    float64
+	Blunder bool
+}
+
+type GameAnalysis struct {
+	Moves     []MoveAnalysis
+	TotalLoss [2]float64
+	Blunders  [2]int
+}
+
+// AnalyzeGame replays a finished game and scores every decision against the
+// best action the model finds from the same public information.
+func AnalyzeGame(r GameRecord, model *LinearModel) (*GameAnalysis, error) {
+	a := &GameAnalysis{}
+	g, _ := r.Replay(0)
+	for _, m := range r.History {
+		values := model.ActionValues(StateFromGame(g))
+		best, _ := BestAction(values)
+		ma := MoveAnalysis{Move: m, Values: values, Best: best}
+		for _, v := range values {
+			if v.Event.EventType == m.EventType && (m.EventType == DRAW_CARDS || v.Event.Target == m.Target) {
+				ma.Chosen = v.Value
+			}
+		}
+		ma.Loss = best.Value - ma.Chosen
+		ma.Blunder = ma.Loss >= BLUNDER_LOSS
+		a.TotalLoss[m.Player] += ma.Loss
+		if ma.Blunder {
+			a.Blunders[m.Player] += 1
+		}
+		a.Moves = append(a.Moves, ma)
+
+		if _, err := g.ApplyMove(AgentEvent{EventType: m.EventType, Target: m.Target}); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
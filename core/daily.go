@@ -0,0 +1,139 @@
+package core
+
+import (
+	"time"
+
+	"github.com/prizelobby/pyramid-rummy/storage"
+)
+
+// the daily challenge is always against the same agent with the standard rules
+const DAILY_AGENT = "model"
+const DAILY_DATE_FORMAT = "2006-01-02"
+
+var DAILY_HISTORY_KEY = storage.Key{Namespace: "stats", Name: "daily", Version: 1}
+
+// DailyDate is the calendar day of t, which names that day's challenge.
+func DailyDate(t time.Time) string {
+	return t.Format(DAILY_DATE_FORMAT)
+}
+
+// DailySeed deals the challenge for a day, read as the number yyyymmdd so the
+// seed of any day is easy to work out by hand.
+func DailySeed(date string) (int64, error) {
+	t, err := time.Parse(DAILY_DATE_FORMAT, date)
+	if err != nil {
+		return 0, err
+	}
+	return int64(t.Year()*10000 + int(t.Month())*100 + t.Day()), nil
+}
+
+// NewDailyGame sets up the challenge for a day, with the player in the first
+// seat and the daily agent in the second.
+func NewDailyGame(date string) (*Game, GameAgent, error) {
+	seed, err := DailySeed(date)
+	if err != nil {
+		return nil, nil, err
+	}
+	agent, err := NewAgent(DAILY_AGENT, 1, seed*2+1, StandardRules)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewVariantGame(seed, StandardRules), agent, nil
+}
+
+type DailyResult struct {
+	Date     string `json:"date"`
+	Score    int    `json:"score"`
+	OppScore int    `json:"opp_score"`
+	Outcome  string `json:"outcome"` // p1, p2 or draw, the player is p1
+	Code     string `json:"code"`    // share code of the game
+}
+
+func (r DailyResult) Won() bool {
+	return r.Outcome == "p1"
+}
+
+// DailyHistory is every daily challenge played on this machine, oldest first.
+type DailyHistory struct {
+	Results []DailyResult `json:"results"`
+}
+
+func LoadDailyHistory() (*DailyHistory, error) {
+	h := &DailyHistory{}
+	err := DAILY_HISTORY_KEY.Load(h)
+	return h, err
+}
+
+func (h *DailyHistory) Save() error {
+	return DAILY_HISTORY_KEY.Save(h)
+}
+
+func NewDailyResult(date string, g *Game) DailyResult {
+	code, _ := ShareCode(g.Record())
+	return DailyResult{
+		Date:     date,
+		Score:    g.Pyramid1.Score(),
+		OppScore: g.Pyramid2.Score(),
+		Outcome:  OutcomeName(g.State),
+		Code:     code,
+	}
+}
+
+func (h *DailyHistory) Result(date string) (DailyResult, bool) {
+	for _, r := range h.Results {
+		if r.Date == date {
+			return r, true
+		}
+	}
+	return DailyResult{}, false
+}
+
+// Add keeps the first result of each day, so replaying a challenge does not
+// change its result. It reports whether the result was kept.
+func (h *DailyHistory) Add(r DailyResult) bool {
+	if _, ok := h.Result(r.Date); ok {
+		return false
+	}
+	h.Results = append(h.Results, r)
+	return true
+}
+
+// streak counts the days in a row ending on today, or on yesterday if today's
+// challenge has not been played yet, whose results pass keep.
+func (h *DailyHistory) streak(today string, keep func(DailyResult) bool) int {
+	t, err := time.Parse(DAILY_DATE_FORMAT, today)
+	if err != nil {
+		return 0
+	}
+	if _, ok := h.Result(today); !ok {
+		t = t.AddDate(0, 0, -1)
+	}
+	n := 0
+	for {
+		r, ok := h.Result(DailyDate(t))
+		if !ok || !keep(r) {
+			return n
+		}
+		n += 1
+		t = t.AddDate(0, 0, -1)
+	}
+}
+
+// Streak is how many days in a row the challenge has been played.
+func (h *DailyHistory) Streak(today string) int {
+	return h.streak(today, func(DailyResult) bool { return true })
+}
+
+// WinStreak is how many days in a row the challenge has been won.
+func (h *DailyHistory) WinStreak(today string) int {
+	return h.streak(today, DailyResult.Won)
+}
+
+func (h *DailyHistory) BestStreak() int {
+	best := 0
+	for _, r := range h.Results {
+		n := h.streak(r.Date, func(DailyResult) bool { return true })
+		best = max(best, n)
+	}
+	return best
+}
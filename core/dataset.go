@@ -0,0 +1,212 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DecisionRecord describes one decision made during a bot game.
+type DecisionRecord struct {
+	Game        int                `json:"game"`
+	Seed        int64              `json:"seed"`
+	Turn        int                `json:"turn"`
+	Player      int                `json:"player"`
+	Agent       string             `json:"agent"`
+	DrawsLeft   int                `json:"draws_left"`
+	Visible     string             `json:"visible"`
+	Features    []float64          `json:"features"`
+	Actions     []string           `json:"actions"`
+	Chosen      string             `json:"chosen"`
+	AgentValues map[string]float64 `json:"agent_values,omitempty"`
+	AgentUnit   string             `json:"agent_unit,omitempty"` // what AgentValues estimate
+	ModelValues map[string]float64 `json:"model_values"`         // always VALUE_MARGIN
+
+	FinalScore    int    `json:"final_score"`
+	OpponentScore int    `json:"opponent_score"`
+	Outcome       string `json:"outcome"`
+}
+
+type DatasetConfig struct {
+	Games  int
+	Seed   int64 // game i is dealt with Seed+i
+	Agents [2]string
+	Rules  Rules
+	Model  *LinearModel // weights for model seats and the reference estimates
+}
+
+// VALUE_SCORE and VALUE_MARGIN say what action values estimate: the player's
+// own final score, or their final score less the opponent's.
+const VALUE_SCORE = "score"
+const VALUE_MARGIN = "margin"
+
+// valueUnit is what the values of an evaluating agent estimate. The sample
+// agent only looks at its own pyramid, the model at both.
+func valueUnit(a GameAgent) string {
+	if _, ok := a.(*SampleAgent); ok {
+		return VALUE_SCORE
+	}
+	return VALUE_MARGIN
+}
+
+func valueMap(values []ActionValue) map[string]float64 {
+	m := make(map[string]float64, len(values))
+	for _, v := range values {
+		m[v.Event.String()] = v.Value
+	}
+	return m
+}
+
+// datasetAgent records the position and estimates before every move the
+// wrapped agent makes.
+type datasetAgent struct {
+	GameAgent
+	name    string
+	player  int
+	game    *Game
+	model   *LinearModel
+	records *[]DecisionRecord
+}
+
+func (d *datasetAgent) GenerateMove() AgentEvent {
+	s := StateFromGame(d.game)
+	f := s.Features()
+	rec := DecisionRecord{
+		Turn:        d.game.Turn,
+		Player:      d.player,
+		Agent:       d.name,
+		DrawsLeft:   d.game.DrawsLeft,
+		Features:    f[:],
+		ModelValues: valueMap(d.model.ActionValues(s)),
+	}
+	if c := s.Visible(); c != nil {
+		rec.Visible = c.String()
+	}
+	for _, a := range s.LegalActions() {
+		rec.Actions = append(rec.Actions, a.String())
+	}
+	if e, ok := d.GameAgent.(ActionEvaluator); ok {
+		rec.AgentValues = valueMap(e.ActionValues())
+		rec.AgentUnit = valueUnit(d.GameAgent)
+	}
+	m := d.GameAgent.GenerateMove()
+	rec.Chosen = m.String()
+	*d.records = append(*d.records, rec)
+	return m
+}
+
+// GenerateDataset plays config.Games seeded games and passes every decision
+// to emit once the game it belongs to has finished.
+func GenerateDataset(config DatasetConfig, emit func(DecisionRecord) error) error {
+	model := config.Model
+	if model == nil {
+		model = DefaultModel
+	}
+	if config.Rules.Name == "" {
+		config.Rules = StandardRules
+	}
+	for i := range config.Games {
+		seed := config.Seed + int64(i)
+		g := NewVariantGame(seed, config.Rules)
+		records := []DecisionRecord{}
+		var agents [2]GameAgent
+		for p := range 2 {
+			a, err := NewAgent(config.Agents[p], p, seed*2+int64(p), config.Rules)
+			if err != nil {
+				return err
+			}
+			if ma, ok := a.(*ModelAgent); ok {
+				ma.Model = model
+			}
+			agents[p] = &datasetAgent{
+				GameAgent: a,
+				name:      config.Agents[p],
+				player:    p,
+				game:      g,
+				model:     model,
+				records:   &records,
+			}
+		}
+		if err := RunGame(g, agents); err != nil {
+			return err
+		}
+
+		scores := [2]int{g.Pyramid1.Score(), g.Pyramid2.Score()}
+		for _, rec := range records {
+			rec.Game = i
+			rec.Seed = seed
+			rec.FinalScore = scores[rec.Player]
+			rec.OpponentScore = scores[1-rec.Player]
+			if rec.FinalScore > rec.OpponentScore {
+				rec.Outcome = "win"
+			} else if rec.FinalScore < rec.OpponentScore {
+				rec.Outcome = "loss"
+			} else {
+				rec.Outcome = "draw"
+			}
+			if err := emit(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func WriteJSONLRecord(w io.Writer, rec DecisionRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func DatasetCSVHeader() []string {
+	header := []string{"game", "seed", "turn", "player", "agent", "draws_left", "visible"}
+	for _, name := range FeatureNames {
+		header = append(header, "f_"+name)
+	}
+	return append(header, "actions", "chosen", "agent_values", "agent_unit", "model_values", "final_score", "opponent_score", "outcome")
+}
+
+func joinValues(m map[string]float64) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + strconv.FormatFloat(m[k], 'f', 3, 64)
+	}
+	return strings.Join(parts, ";")
+}
+
+func WriteCSVRecord(w *csv.Writer, rec DecisionRecord) error {
+	row := []string{
+		strconv.Itoa(rec.Game),
+		strconv.FormatInt(rec.Seed, 10),
+		strconv.Itoa(rec.Turn),
+		strconv.Itoa(rec.Player),
+		rec.Agent,
+		strconv.Itoa(rec.DrawsLeft),
+		rec.Visible,
+	}
+	for _, f := range rec.Features {
+		row = append(row, strconv.FormatFloat(f, 'f', 3, 64))
+	}
+	row = append(row,
+		strings.Join(rec.Actions, ";"),
+		rec.Chosen,
+		joinValues(rec.AgentValues),
+		rec.AgentUnit,
+		joinValues(rec.ModelValues),
+		strconv.Itoa(rec.FinalScore),
+		strconv.Itoa(rec.OpponentScore),
+		rec.Outcome,
+	)
+	return w.Write(row)
+}
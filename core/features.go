@@ -0,0 +1,245 @@
+package core
+
+import "math"
+
+// DecisionState is everything a player can see when it is their turn to act.
+type DecisionState struct {
+	Own       *Pyramid
+	Opp       *Pyramid
+	Discards  []*Card // the revealed stack, top card last
+	Unseen    [20]int // copies not yet revealed, indexed by TypeIndex
+	DrawsLeft int
+}
+
+// TypeIndex identifies a card by value and color, ignoring which copy it is.
+func TypeIndex(c *Card) int {
+	return c.Color*10 + c.Value - 1
+}
+
+func TypeToCard(t int) *Card {
+	return &Card{Value: t%10 + 1, Color: t / 10}
+}
+
+func NewDecisionState(own, opp *Pyramid, discards []*Card, seen [40]bool, drawsLeft int) *DecisionState {
+	s := &DecisionState{
+		Own:       own,
+		Opp:       opp,
+		Discards:  discards,
+		DrawsLeft: drawsLeft,
+	}
+	for i := range 40 {
+		if !seen[i] {
+			s.Unseen[TypeIndex(IndexToCard(i))] += 1
+		}
+	}
+	return s
+}
+
+// StateFromGame builds the decision state for the player to move using only
+// public information, so the deck order is not leaked.
+func StateFromGame(g *Game) *DecisionState {
+	own, opp := g.Pyramid1, g.Pyramid2
+	if g.CurrentPlayer() == 1 {
+		own, opp = opp, own
+	}
+	var seen [40]bool
+	for _, p := range []*Pyramid{g.Pyramid1, g.Pyramid2} {
+		for _, c := range p.Cards {
+			if c != nil {
+				seen[CardToIndex(c)] = true
+			}
+		}
+	}
+	for _, c := range g.Discards {
+		seen[CardToIndex(c)] = true
+	}
+	return NewDecisionState(own, opp, g.Discards, seen, g.DrawsLeft)
+}
+
+func (s *DecisionState) Visible() *Card {
+	if l := len(s.Discards); l != 0 {
+		return s.Discards[l-1]
+	}
+	return nil
+}
+
+// Gift is the card the opponent is offered if the visible card is placed.
+func (s *DecisionState) Gift() *Card {
+	if l := len(s.Discards); l > 1 {
+		return s.Discards[l-2]
+	}
+	return nil
+}
+
+func (s *DecisionState) UnseenCount() int {
+	n := 0
+	for _, u := range s.Unseen {
+		n += u
+	}
+	return n
+}
+
+// ColorChance is the chance that the next card drawn is of the given color.
+func (s *DecisionState) ColorChance(color int) float64 {
+	n := 0
+	for v := range 10 {
+		n += s.Unseen[color*10+v]
+	}
+	return chance(n, s.UnseenCount())
+}
+
+// AtLeastChance is the chance that the next card drawn is worth value or more.
+func (s *DecisionState) AtLeastChance(value int) float64 {
+	n := 0
+	for t, u := range s.Unseen {
+		if TypeToCard(t).Value >= value {
+			n += u
+		}
+	}
+	return chance(n, s.UnseenCount())
+}
+
+func chance(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func (s *DecisionState) CanDraw() bool {
+	return s.DrawsLeft > 0 && s.UnseenCount() > 0
+}
+
+// LegalActions lists the draw (if allowed) followed by every open slot the
+// visible card could be placed in.
+func (s *DecisionState) LegalActions() []AgentEvent {
+	actions := []AgentEvent{}
+	if s.CanDraw() {
+		actions = append(actions, AgentEvent{EventType: DRAW_CARDS})
+	}
+	if s.Visible() != nil {
+		for i := range 10 {
+			if s.Own.CanPlace(i) {
+				actions = append(actions, AgentEvent{EventType: PLAY_CARD, Target: i})
+			}
+		}
+	}
+	return actions
+}
+
+const NUM_FEATURES = 10
+
+var FeatureNames = [NUM_FEATURES]string{
+	"bias",
+	"score",
+	"mixed_pairs",
+	"mono_pairs",
+	"open_singles",
+	"color_balance",
+	"high_demand",
+	"empty_slots",
+	"gift_value",
+	"gift_gain",
+}
+
+// pyramidFeatures are the per pyramid terms of the feature vector. The
+// expectations are taken over the unseen cards.
+func pyramidFeatures(cards *[10]*Card, unseen *[20]int) [NUM_FEATURES]float64 {
+	var f [NUM_FEATURES]float64
+
+	var colorCount, colorValue, colorHigh [2]float64
+	total := 0.0
+	for t, u := range unseen {
+		c := t / 10
+		colorCount[c] += float64(u)
+		colorValue[c] += float64(u * (t%10 + 1))
+		if t%10+1 >= 8 {
+			colorHigh[c] += float64(u)
+		}
+		total += float64(u)
+	}
+	var pColor, meanValue [2]float64
+	for c := range 2 {
+		if total > 0 {
+			pColor[c] = colorCount[c] / total
+		}
+		if colorCount[c] > 0 {
+			meanValue[c] = colorValue[c] / colorCount[c]
+		}
+	}
+
+	placed := [2]int{}
+	for _, c := range cards {
+		if c != nil {
+			placed[c.Color] += 1
+		}
+	}
+
+	p := Pyramid{Cards: *cards}
+	f[1] = float64(p.Score())
+	for _, e := range Edges {
+		present := make([]*Card, 0, 3)
+		for _, i := range e {
+			if cards[i] != nil {
+				present = append(present, cards[i])
+			}
+		}
+		if len(present) == 1 {
+			f[4] += 1
+		} else if len(present) == 2 {
+			a, b := present[0], present[1]
+			if a.Color != b.Color {
+				// the third card matches one of the two, leaving the other odd
+				f[2] += pColor[a.Color]*float64(b.Value) + pColor[b.Color]*float64(a.Value)
+			} else {
+				other := 1 - a.Color
+				f[3] += pColor[other] * meanValue[other]
+				if total > 0 {
+					f[6] += colorHigh[other] / total
+				}
+			}
+		}
+	}
+	f[5] = math.Abs(float64(placed[0] - placed[1]))
+	f[7] = float64(10 - placed[0] - placed[1])
+	return f
+}
+
+// bestGain is the most the pyramid's score can go up by placing c.
+func bestGain(p *Pyramid, c *Card) float64 {
+	base := p.Score()
+	best := 0
+	for i := range 10 {
+		if p.CanPlace(i) {
+			best = max(best, p.TentativeScoreWithCard(c, i)-base)
+		}
+	}
+	return float64(best)
+}
+
+// MarginFeatures describes a position right after a card has been placed: the
+// difference between the two pyramids plus the card left for the opponent.
+func MarginFeatures(own, opp *[10]*Card, gift *Card, unseen *[20]int) [NUM_FEATURES]float64 {
+	f := pyramidFeatures(own, unseen)
+	if opp != nil {
+		o := pyramidFeatures(opp, unseen)
+		for i := range f {
+			f[i] -= o[i]
+		}
+		if gift != nil {
+			f[8] = float64(gift.Value)
+			f[9] = bestGain(&Pyramid{Cards: *opp}, gift)
+		}
+	}
+	f[0] = 1
+	return f
+}
+
+// Features describes the position before the player to move has acted.
+func (s *DecisionState) Features() [NUM_FEATURES]float64 {
+	var opp *[10]*Card
+	if s.Opp != nil {
+		opp = &s.Opp.Cards
+	}
+	return MarginFeatures(&s.Own.Cards, opp, nil, &s.Unseen)
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"math/rand"
 	"strconv"
 	"time"
@@ -13,6 +14,7 @@ const (
 	P1_WIN
 	P2_WIN
 	DRAW
+	FINISHED // a solo game has no winner
 )
 
 type SourceLocation int
@@ -24,6 +26,8 @@ const (
 )
 
 type Game struct {
+	Seed      int64
+	Rules     Rules
 	Rand      *rand.Rand
 	Deck      []*Card
 	Discards  []*Card
@@ -32,12 +36,35 @@ type Game struct {
 	Turn      int
 	State     GameState
 	DrawsLeft int
+	History   []Move
+	Start     string // position the game was set up from, empty for a new deal
+	Solo      bool   // one player building Pyramid1 alone
+}
+
+// Move is one decision taken during a game, with the card it revealed or
+// placed.
+type Move struct {
+	Player    int
+	EventType EventType
+	Target    int
+	Card      *Card
 }
 
 func (g *Game) CurrentPlayer() int {
+	if g.Solo {
+		return 0
+	}
 	return g.Turn % 2
 }
 
+// Turns is how many turns the game lasts.
+func (g *Game) Turns() int {
+	if g.Solo {
+		return 10
+	}
+	return 20
+}
+
 func (g *Game) TopDiscard() *Card {
 	if l := len(g.Discards); l != 0 {
 		return g.Discards[l-1]
@@ -46,7 +73,7 @@ func (g *Game) TopDiscard() *Card {
 }
 
 func (g *Game) DrawCard() *Card {
-	if g.DrawsLeft == 0 {
+	if g.DrawsLeft == 0 || len(g.Deck) == 0 {
 		//fmt.Println("Game: Trying to draw with 0 draws left")
 		return nil
 	}
@@ -54,6 +81,7 @@ func (g *Game) DrawCard() *Card {
 	c := g.Deck[0]
 	g.Discards = append(g.Discards, c)
 	g.Deck = g.Deck[1:]
+	g.History = append(g.History, Move{Player: g.CurrentPlayer(), EventType: DRAW_CARDS, Card: c})
 	//fmt.Println("Game: Drew card " + c.String())
 	return c
 }
@@ -62,13 +90,25 @@ func (g *Game) PlayCard(target int) *Card {
 	c := g.Discards[len(g.Discards)-1]
 	g.Discards = g.Discards[:len(g.Discards)-1]
 	// UI should prevent from making illegal moves
-	if g.Turn%2 == 0 {
+	g.History = append(g.History, Move{Player: g.CurrentPlayer(), EventType: PLAY_CARD, Target: target, Card: c})
+	if g.CurrentPlayer() == 0 {
 		g.Pyramid1.Cards[target] = c
 	} else {
 		g.Pyramid2.Cards[target] = c
 	}
 	g.Turn += 1
-	g.DrawsLeft = 2
+	g.DrawsLeft = g.Rules.DrawsPerTurn
+	g.checkGameOver()
+	return c
+}
+
+func (g *Game) checkGameOver() {
+	if g.Solo {
+		if g.Turn == g.Turns() {
+			g.State = FINISHED
+		}
+		return
+	}
 	if g.Turn == 20 {
 		s1 := g.Pyramid1.Score()
 		s2 := g.Pyramid2.Score()
@@ -80,7 +120,6 @@ func (g *Game) PlayCard(target int) *Card {
 			g.State = DRAW
 		}
 	}
-	return c
 }
 
 /*
@@ -127,19 +166,32 @@ func (p *Pyramid) TentativeScoreWithCard(c *Card, i int) int {
 func (p *Pyramid) Score() int {
 	score := 0
 	for i := range 6 {
-		d := Edges[i]
-		if p.Cards[d[0]] == nil || p.Cards[d[1]] == nil || p.Cards[d[2]] == nil {
-			continue
-		}
-		for j := range 3 {
-			if p.Cards[d[j]].Color != p.Cards[d[(j+1)%3]].Color && p.Cards[d[j]].Color != p.Cards[d[(j+2)%3]].Color {
-				score += p.Cards[d[j]].Value
-			}
-		}
+		s, _ := p.EdgeScore(i)
+		score += s
 	}
 	return score
 }
 
+func (p *Pyramid) EdgeComplete(i int) bool {
+	d := Edges[i]
+	return p.Cards[d[0]] != nil && p.Cards[d[1]] != nil && p.Cards[d[2]] != nil
+}
+
+// EdgeScore scores edge i on its own and returns the slot of the card that
+// scored it, or -1 if the edge is not complete or all one color.
+func (p *Pyramid) EdgeScore(i int) (int, int) {
+	if !p.EdgeComplete(i) {
+		return 0, -1
+	}
+	d := Edges[i]
+	for j := range 3 {
+		if p.Cards[d[j]].Color != p.Cards[d[(j+1)%3]].Color && p.Cards[d[j]].Color != p.Cards[d[(j+2)%3]].Color {
+			return p.Cards[d[j]].Value, d[j]
+		}
+	}
+	return 0, -1
+}
+
 func (p *Pyramid) CanPlace(i int) bool {
 	if p.Cards[i] != nil {
 		return false
@@ -176,6 +228,103 @@ func (c Card) String() string {
 	return v + cs
 }
 
+// CanMove reports whether the event is legal for the player to move.
+func (g *Game) CanMove(e AgentEvent) bool {
+	if g.State != IN_PROGRESS {
+		return false
+	}
+	if e.EventType == DRAW_CARDS {
+		return g.DrawsLeft > 0 && len(g.Deck) > 0
+	}
+	if g.TopDiscard() == nil || e.Target < 0 || e.Target >= 10 {
+		return false
+	}
+	p := g.Pyramid1
+	if g.CurrentPlayer() == 1 {
+		p = g.Pyramid2
+	}
+	return p.CanPlace(e.Target)
+}
+
+// LegalMoves lists the moves the player to move can make, drawing first.
+func (g *Game) LegalMoves() []AgentEvent {
+	moves := []AgentEvent{}
+	if e := (AgentEvent{EventType: DRAW_CARDS}); g.CanMove(e) {
+		moves = append(moves, e)
+	}
+	for i := range 10 {
+		if e := (AgentEvent{EventType: PLAY_CARD, Target: i}); g.CanMove(e) {
+			moves = append(moves, e)
+		}
+	}
+	return moves
+}
+
+// HasLegalMove reports whether the player to move can draw or place the open
+// card anywhere.
+func (g *Game) HasLegalMove() bool {
+	return len(g.LegalMoves()) > 0
+}
+
+// ApplyMove makes the move for the player to move, returning an error instead
+// of corrupting the game if it is not legal.
+func (g *Game) ApplyMove(e AgentEvent) (*Card, error) {
+	if !g.CanMove(e) {
+		return nil, errors.New("illegal move " + e.String() + " on turn " + strconv.Itoa(g.Turn+1))
+	}
+	if e.EventType == DRAW_CARDS {
+		return g.DrawCard(), nil
+	}
+	return g.PlayCard(e.Target), nil
+}
+
+// GameRecord holds everything needed to reproduce a game exactly.
+type GameRecord struct {
+	Seed    int64
+	Rules   Rules
+	Start   string
+	Solo    bool
+	History []Move
+}
+
+func (g *Game) Record() GameRecord {
+	return GameRecord{Seed: g.Seed, Rules: g.Rules, Start: g.Start, Solo: g.Solo, History: g.History}
+}
+
+// Replay deals the recorded game and applies its first n moves.
+func (r GameRecord) Replay(n int) (*Game, error) {
+	g, err := r.NewGame()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range r.History[:n] {
+		c, err := g.ApplyMove(AgentEvent{EventType: m.EventType, Target: m.Target})
+		if err != nil {
+			return nil, err
+		}
+		if m.Card != nil && (c.Value != m.Card.Value || c.Color != m.Card.Color) {
+			return nil, errors.New("history does not match the deal for this seed")
+		}
+	}
+	return g, nil
+}
+
+// NewGame sets up the recorded game before any of its moves.
+func (r GameRecord) NewGame() (*Game, error) {
+	if r.Start != "" {
+		g, err := NewGameFromPosition(r.Start, r.Seed)
+		if err != nil {
+			return nil, err
+		}
+		g.Solo = r.Solo
+		return g, nil
+	}
+	if r.Solo {
+		return NewSoloGame(r.Seed, r.Rules), nil
+	}
+	return NewVariantGame(r.Seed, r.Rules), nil
+}
+
 func NewDeck() [40]*Card {
 	deck := [40]*Card{}
 	for i := range 10 {
@@ -188,8 +337,16 @@ func NewDeck() [40]*Card {
 }
 
 func NewGame() *Game {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	//r := rand.New(rand.NewSource(0))
+	return NewSeededGame(time.Now().UnixNano())
+}
+
+// NewSeededGame deals a game whose deck order is fully determined by seed.
+func NewSeededGame(seed int64) *Game {
+	return NewVariantGame(seed, StandardRules)
+}
+
+func NewVariantGame(seed int64, rules Rules) *Game {
+	r := rand.New(rand.NewSource(seed))
 	deck := NewDeck()
 	r.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
 
@@ -198,12 +355,14 @@ func NewGame() *Game {
 	//deck = deck[1:]
 
 	return &Game{
+		Seed:      seed,
+		Rules:     rules,
 		Rand:      r,
 		Deck:      deck[:],
 		Discards:  discards,
 		Pyramid1:  &Pyramid{Cards: [10]*Card{}},
 		Pyramid2:  &Pyramid{Cards: [10]*Card{}},
 		Turn:      0,
-		DrawsLeft: 2,
+		DrawsLeft: rules.DrawsPerTurn,
 	}
 }
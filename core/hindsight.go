@@ -0,0 +1,293 @@
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// TurnOffer is what a player had to choose from on one of their turns: the
+// revealed card and the cards they could have drawn, in deck order.
+type TurnOffer struct {
+	Player int
+	Top    *Card
+	Draws  []*Card
+	Opp    [10]*Card
+	Unseen [20]int
+}
+
+func (o *TurnOffer) Cards() []*Card {
+	cards := make([]*Card, 0, 3)
+	if o.Top != nil {
+		cards = append(cards, o.Top)
+	}
+	return append(cards, o.Draws...)
+}
+
+// OfferedCards replays a game and returns the offers for each player's turns.
+// The offers follow the game as it was actually played.
+func OfferedCards(r GameRecord) ([2][]TurnOffer, error) {
+	var offers [2][]TurnOffer
+	g, _ := r.Replay(0)
+	startOfTurn := true
+	for _, m := range r.History {
+		if startOfTurn {
+			p := g.CurrentPlayer()
+			opp := g.Pyramid2
+			if p == 1 {
+				opp = g.Pyramid1
+			}
+			offers[p] = append(offers[p], TurnOffer{
+				Player: p,
+				Top:    g.TopDiscard(),
+				Draws:  append([]*Card{}, g.Deck[:min(g.DrawsLeft, len(g.Deck))]...),
+				Opp:    opp.Cards,
+				Unseen: StateFromGame(g).Unseen,
+			})
+		}
+		if _, err := g.ApplyMove(AgentEvent{EventType: m.EventType, Target: m.Target}); err != nil {
+			return offers, err
+		}
+		startOfTurn = m.EventType == PLAY_CARD
+	}
+	return offers, nil
+}
+
+func edgeScore(a, b, c *Card) int {
+	if a.Color == b.Color && b.Color == c.Color {
+		return 0
+	}
+	if b.Color == c.Color {
+		return a.Value
+	}
+	if a.Color == c.Color {
+		return b.Value
+	}
+	return c.Value
+}
+
+var slotOrders [][10]int
+var slotOrdersOnce sync.Once
+
+// SlotOrders lists every order the ten slots of a pyramid can be filled in.
+func SlotOrders() [][10]int {
+	slotOrdersOnce.Do(func() {
+		var order [10]int
+		var fill func(p *Pyramid, n int)
+		fill = func(p *Pyramid, n int) {
+			if n == 10 {
+				slotOrders = append(slotOrders, order)
+				return
+			}
+			for i := range 10 {
+				if p.CanPlace(i) {
+					order[n] = i
+					p.Cards[i] = &Card{}
+					fill(p, n+1)
+					p.Cards[i] = nil
+				}
+			}
+		}
+		fill(&Pyramid{}, 0)
+	})
+	return slotOrders
+}
+
+// each edge is two of the corners or apex plus one midpoint that only belongs
+// to that edge
+var corners = [4]int{0, 3, 5, 9}
+var edgeCorners = [6][2]int{{0, 1}, {0, 2}, {1, 2}, {0, 3}, {1, 3}, {2, 3}}
+var edgeMidpoints = [6]int{1, 2, 4, 6, 7, 8}
+
+// BestPyramid finds the highest scoring pyramid that could have been built
+// from ten turns of offers, taking one card per turn and filling the slots in
+// a legal order. A card left on the stack is offered again on later turns, but
+// it can only be taken once.
+func BestPyramid(offers []TurnOffer) (int, [10]*Card, error) {
+	var best [10]*Card
+	if len(offers) != 10 {
+		return 0, best, errors.New("hindsight needs all ten turns of a player")
+	}
+	cards := make([][]*Card, 10)
+	for t := range offers {
+		cards[t] = offers[t].Cards()
+		if len(cards[t]) == 0 {
+			return 0, best, errors.New("a turn offered no cards")
+		}
+	}
+
+	bestScore := -1
+	used := map[*Card]bool{}
+	for _, order := range SlotOrders() {
+		var turnOf [10]int
+		for t, slot := range order {
+			turnOf[slot] = t
+		}
+		var fixed [4]*Card
+		var pick func(k int)
+		pick = func(k int) {
+			if k < 4 {
+				for _, c := range cards[turnOf[corners[k]]] {
+					if used[c] {
+						continue
+					}
+					fixed[k] = c
+					used[c] = true
+					pick(k + 1)
+					used[c] = false
+				}
+				return
+			}
+			score, mids := bestMidpoints(fixed, turnOf, cards, used, bestScore)
+			if score > bestScore {
+				bestScore = score
+				for k, c := range corners {
+					best[c] = fixed[k]
+				}
+				for e, m := range edgeMidpoints {
+					best[m] = mids[e]
+				}
+			}
+		}
+		pick(0)
+	}
+	if bestScore < 0 {
+		return 0, best, errors.New("the offers can't fill a pyramid")
+	}
+	if !distinctCards(best) {
+		return 0, best, errors.New("hindsight used a card twice")
+	}
+	return bestScore, best, nil
+}
+
+// bestMidpoints picks a midpoint for each edge between the fixed corners,
+// each a different card, for the highest total. Edges are tried with their
+// best card first, so the search only goes deeper when two edges want the same
+// card, and it gives up on anything that can't beat floor.
+func bestMidpoints(fixed [4]*Card, turnOf [10]int, cards [][]*Card, used map[*Card]bool, floor int) (int, [6]*Card) {
+	var top [6]int
+	for e := range 6 {
+		a, b := fixed[edgeCorners[e][0]], fixed[edgeCorners[e][1]]
+		top[e] = -1
+		for _, m := range cards[turnOf[edgeMidpoints[e]]] {
+			top[e] = max(top[e], edgeScore(a, m, b))
+		}
+	}
+	bestScore := floor
+	var best, mids [6]*Card
+	found := false
+	var fill func(e, score int)
+	fill = func(e, score int) {
+		if e == 6 {
+			if score > bestScore {
+				bestScore, best, found = score, mids, true
+			}
+			return
+		}
+		bound := score
+		for _, t := range top[e:] {
+			bound += t
+		}
+		if bound <= bestScore {
+			return
+		}
+		a, b := fixed[edgeCorners[e][0]], fixed[edgeCorners[e][1]]
+		options := append([]*Card{}, cards[turnOf[edgeMidpoints[e]]]...)
+		sort.SliceStable(options, func(i, j int) bool {
+			return edgeScore(a, options[i], b) > edgeScore(a, options[j], b)
+		})
+		for _, m := range options {
+			if used[m] {
+				continue
+			}
+			mids[e] = m
+			used[m] = true
+			fill(e+1, score+edgeScore(a, m, b))
+			used[m] = false
+		}
+	}
+	fill(0, 0)
+	if !found {
+		return -1, best
+	}
+	return bestScore, best
+}
+
+func distinctCards(cards [10]*Card) bool {
+	seen := map[*Card]bool{}
+	for _, c := range cards {
+		if c == nil || seen[c] {
+			return false
+		}
+		seen[c] = true
+	}
+	return true
+}
+
+// ParScore plays the offers with the model, as a stand in for how a typical
+// game with the same cards goes.
+func ParScore(offers []TurnOffer, model *LinearModel) int {
+	own := &Pyramid{}
+	for _, o := range offers {
+		s := &DecisionState{
+			Own:       own,
+			Opp:       &Pyramid{Cards: o.Opp},
+			Discards:  []*Card{},
+			Unseen:    o.Unseen,
+			DrawsLeft: len(o.Draws),
+		}
+		if o.Top != nil {
+			s.Discards = append(s.Discards, o.Top)
+		}
+		for {
+			best, ok := BestAction(model.ActionValues(s))
+			if !ok {
+				break
+			}
+			if best.Event.EventType == PLAY_CARD {
+				own.Cards[best.Event.Target] = s.Visible()
+				break
+			}
+			c := o.Draws[len(o.Draws)-s.DrawsLeft]
+			s.Discards = append(s.Discards, c)
+			s.Unseen[TypeIndex(c)] -= 1
+			s.DrawsLeft -= 1
+		}
+	}
+	return own.Score()
+}
+
+// LuckReport splits a finished game into the cards each player was dealt and
+// how well they used them. Luck is a player's par minus the opponent's par, and
+// Skill is the actual score minus par, so the final margin is luck plus the
+// difference in skill.
+type LuckReport struct {
+	Actual [2]int `json:"actual"`
+	Best   [2]int `json:"best"`
+	Par    [2]int `json:"par"`
+	Luck   [2]int `json:"luck"`
+	Skill  [2]int `json:"skill"`
+}
+
+func MeasureLuck(record GameRecord, model *LinearModel) (*LuckReport, error) {
+	offers, err := OfferedCards(record)
+	if err != nil {
+		return nil, err
+	}
+	g, err := record.Replay(len(record.History))
+	if err != nil {
+		return nil, err
+	}
+	r := &LuckReport{Actual: [2]int{g.Pyramid1.Score(), g.Pyramid2.Score()}}
+	for p := range 2 {
+		r.Best[p], _, err = BestPyramid(offers[p])
+		if err != nil {
+			return nil, err
+		}
+		r.Par[p] = ParScore(offers[p], model)
+		r.Skill[p] = r.Actual[p] - r.Par[p]
+	}
+	r.Luck[0] = r.Par[0] - r.Par[1]
+	r.Luck[1] = -r.Luck[0]
+	return r, nil
+}
@@ -0,0 +1,37 @@
+package core
+
+import "testing"
+
+func playSampleGame(t *testing.T, seed int64) *Game {
+	t.Helper()
+	g := NewVariantGame(seed, StandardRules)
+	if err := RunGame(g, sampleAgents(t, seed)); err != nil {
+		t.Fatalf("seed %d: %v", seed, err)
+	}
+	return g
+}
+
+func TestBestPyramidUsesEachCardOnce(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		g := playSampleGame(t, seed)
+		offers, err := OfferedCards(g.Record())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for p, actual := range []*Pyramid{g.Pyramid1, g.Pyramid2} {
+			score, best, err := BestPyramid(offers[p])
+			if err != nil {
+				t.Fatalf("seed %d player %d: %v", seed, p, err)
+			}
+			if !distinctCards(best) {
+				t.Errorf("seed %d player %d: a card fills two slots", seed, p)
+			}
+			if s := (&Pyramid{Cards: best}).Score(); s != score {
+				t.Errorf("seed %d player %d: best pyramid scores %d, reported %d", seed, p, s, score)
+			}
+			if score < actual.Score() {
+				t.Errorf("seed %d player %d: best %d is below the actual %d", seed, p, score, actual.Score())
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// LinearModel estimates the final score margin (own minus opponent) of a
+// position from its MarginFeatures.
+type LinearModel struct {
+	Weights [NUM_FEATURES]float64
+}
+
+// DefaultModel holds weights from a short self-play run. It is used when no
+// trained weights are loaded and as the first generation of training.
+var DefaultModel = &LinearModel{
+	Weights: [NUM_FEATURES]float64{0.4, 1.06, 0.89, 0.64, 1.37, 0.28, 2, 5.1, 0.03, -0.04},
+}
+
+type ActionValue struct {
+	Event AgentEvent
+	Value float64
+}
+
+func (m *LinearModel) Evaluate(f [NUM_FEATURES]float64) float64 {
+	v := 0.0
+	for i := range f {
+		v += m.Weights[i] * f[i]
+	}
+	return v
+}
+
+func (m *LinearModel) placeValue(s *DecisionState, c *Card, gift *Card, slot int) float64 {
+	own := s.Own.Cards
+	own[slot] = c
+	var opp *[10]*Card
+	if s.Opp != nil {
+		opp = &s.Opp.Cards
+	}
+	return m.Evaluate(MarginFeatures(&own, opp, gift, &s.Unseen))
+}
+
+// drawValue is the expected value of drawing with drawsLeft draws remaining,
+// assuming the best choice is made once the card is revealed.
+func (m *LinearModel) drawValue(s *DecisionState, top *Card, drawsLeft int) float64 {
+	total := 0
+	value := 0.0
+	for t, u := range s.Unseen {
+		if u == 0 {
+			continue
+		}
+		c := TypeToCard(t)
+		s.Unseen[t] -= 1
+		best := 0.0
+		first := true
+		for i := range 10 {
+			if s.Own.CanPlace(i) {
+				v := m.placeValue(s, c, top, i)
+				if first || v > best {
+					best = v
+					first = false
+				}
+			}
+		}
+		if drawsLeft > 1 && s.UnseenCount() > 0 {
+			if v := m.drawValue(s, c, drawsLeft-1); first || v > best {
+				best = v
+			}
+		}
+		s.Unseen[t] += 1
+		total += u
+		value += float64(u) * best
+	}
+	if total == 0 {
+		return 0
+	}
+	return value / float64(total)
+}
+
+// ActionValues scores every legal action in s by the expected final margin.
+func (m *LinearModel) ActionValues(s *DecisionState) []ActionValue {
+	actions := s.LegalActions()
+	values := make([]ActionValue, len(actions))
+	for i, a := range actions {
+		values[i].Event = a
+		if a.EventType == DRAW_CARDS {
+			values[i].Value = m.drawValue(s, s.Visible(), s.DrawsLeft)
+		} else {
+			values[i].Value = m.placeValue(s, s.Visible(), s.Gift(), a.Target)
+		}
+	}
+	return values
+}
+
+// BestAction returns the highest valued action, or ok == false if there is no
+// legal action.
+func BestAction(values []ActionValue) (best ActionValue, ok bool) {
+	for i, v := range values {
+		if i == 0 || v.Value > best.Value {
+			best = v
+		}
+	}
+	return best, len(values) > 0
+}
+
+type modelFile struct {
+	Features []string  `json:"features"`
+	Weights  []float64 `json:"weights"`
+}
+
+func (m *LinearModel) Save(path string) error {
+	f := modelFile{Features: FeatureNames[:], Weights: m.Weights[:]}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func LoadLinearModel(path string) (*LinearModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f modelFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if len(f.Weights) != NUM_FEATURES || len(f.Features) != NUM_FEATURES {
+		return nil, errors.New("model file does not match the current feature set")
+	}
+	for i, name := range f.Features {
+		if name != FeatureNames[i] {
+			return nil, errors.New("model file has unknown feature " + name)
+		}
+	}
+	m := &LinearModel{}
+	copy(m.Weights[:], f.Weights)
+	return m, nil
+}
@@ -0,0 +1,91 @@
+package core
+
+import "math/rand"
+
+// ModelAgent picks the action with the highest value under a LinearModel.
+type ModelAgent struct {
+	PlayerNumber   int
+	Model          *LinearModel
+	Rand           *rand.Rand
+	Epsilon        float64 // chance of a random action, used during training
+	DrawsPerTurn   int
+	DrawsRemaining int
+	VisibleCard    *Card
+	Pyramids       [2]*Pyramid
+	Discards       []*Card
+	SeenCards      [40]bool
+}
+
+func NewModelAgent(playerNumber int, model *LinearModel) *ModelAgent {
+	if model == nil {
+		model = DefaultModel
+	}
+	return &ModelAgent{
+		PlayerNumber:   playerNumber,
+		Model:          model,
+		Rand:           rand.New(rand.NewSource(0)),
+		Pyramids:       [2]*Pyramid{&Pyramid{}, &Pyramid{}},
+		DrawsPerTurn:   2,
+		DrawsRemaining: 2,
+	}
+}
+
+func (a *ModelAgent) State() *DecisionState {
+	return NewDecisionState(a.Pyramids[a.PlayerNumber], a.Pyramids[1-a.PlayerNumber], a.Discards, a.SeenCards, a.DrawsRemaining)
+}
+
+func (a *ModelAgent) ActionValues() []ActionValue {
+	return a.Model.ActionValues(a.State())
+}
+
+func (a *ModelAgent) GenerateMove() AgentEvent {
+	values := a.ActionValues()
+	best, ok := BestAction(values)
+	if !ok {
+		// nothing visible and no draws left should not happen in a legal game
+		return AgentEvent{EventType: DRAW_CARDS}
+	}
+	if a.Epsilon > 0 && a.Rand.Float64() < a.Epsilon {
+		best = values[a.Rand.Intn(len(values))]
+	}
+
+	if best.Event.EventType == DRAW_CARDS {
+		a.DrawsRemaining -= 1
+		return best.Event
+	}
+	a.Pyramids[a.PlayerNumber].Cards[best.Event.Target] = a.VisibleCard
+	a.popDiscard()
+	a.VisibleCard = nil
+	a.DrawsRemaining = a.DrawsPerTurn
+	return best.Event
+}
+
+func (a *ModelAgent) popDiscard() {
+	if l := len(a.Discards); l != 0 {
+		a.Discards = a.Discards[:l-1]
+	}
+}
+
+func (a *ModelAgent) AcceptMove(card *Card, index int) {
+	a.Pyramids[1-a.PlayerNumber].Cards[index] = card
+	a.SeenCards[CardToIndex(card)] = true
+	a.popDiscard()
+	a.DrawsRemaining = a.DrawsPerTurn
+}
+
+func (a *ModelAgent) RevealCard(card *Card) {
+	a.SeenCards[CardToIndex(card)] = true
+	a.Discards = append(a.Discards, card)
+}
+
+func (a *ModelAgent) SetVisibleCard(c *Card) {
+	a.VisibleCard = c
+}
+
+func (a *ModelAgent) SetPosition(g *Game) {
+	a.Pyramids = publicPyramids(g)
+	a.SeenCards = seenInPosition(g)
+	a.Discards = append([]*Card{}, g.Discards...)
+	a.VisibleCard = g.TopDiscard()
+	a.DrawsRemaining = drawsLeftFor(g, a.PlayerNumber, a.DrawsPerTurn)
+}
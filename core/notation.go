@@ -0,0 +1,391 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+Games are written as numbered turns. A draw is a plus sign followed by the card
+drawn and a placement is the card placed followed by @ and the slot.
+
+	1. +4p 4p@0 2. +4y +6p 6p@0 3. 4y@1 ...
+
+An archive is a list of games, each a block of [Name "value"] headers followed
+by the moves and the result, with a blank line between games.
+*/
+
+// ParseCard reads a card code as written by Card.String, such as Ty or 3p.
+func ParseCard(code string) (*Card, error) {
+	if len(code) != 2 {
+		return nil, errors.New("bad card " + strconv.Quote(code))
+	}
+	c := &Card{}
+	switch v := code[0]; {
+	case v == 'T' || v == 't':
+		c.Value = 10
+	case v >= '1' && v <= '9':
+		c.Value = int(v - '0')
+	default:
+		return nil, errors.New("bad card value in " + strconv.Quote(code))
+	}
+	switch code[1] {
+	case 'p':
+		c.Color = 0
+	case 'y':
+		c.Color = 1
+	default:
+		return nil, errors.New("bad card color in " + strconv.Quote(code))
+	}
+	return c, nil
+}
+
+func sameCard(a, b *Card) bool {
+	return a.Value == b.Value && a.Color == b.Color
+}
+
+// Notation writes the move as +Xc for a draw or Xc@N for a placement.
+func (m Move) Notation() string {
+	if m.EventType == DRAW_CARDS {
+		return "+" + m.Card.String()
+	}
+	return m.Card.String() + "@" + strconv.Itoa(m.Target)
+}
+
+// Describe says what the move did in words, calling the players by names.
+func (m Move) Describe(names [2]string) string {
+	if m.EventType == DRAW_CARDS {
+		return names[m.Player] + " draws " + m.Card.String()
+	}
+	return names[m.Player] + " places " + m.Card.String() + " in slot " + strconv.Itoa(m.Target)
+}
+
+// ParseMove reads a single move. The player is not part of the notation and is
+// left for the caller to fill in.
+func ParseMove(token string) (Move, error) {
+	if rest, ok := strings.CutPrefix(token, "+"); ok {
+		c, err := ParseCard(rest)
+		if err != nil {
+			return Move{}, err
+		}
+		return Move{EventType: DRAW_CARDS, Card: c}, nil
+	}
+	code, slot, ok := strings.Cut(token, "@")
+	if !ok {
+		return Move{}, errors.New("bad move " + strconv.Quote(token))
+	}
+	c, err := ParseCard(code)
+	if err != nil {
+		return Move{}, err
+	}
+	target, err := strconv.Atoi(slot)
+	if err != nil || target < 0 || target >= 10 {
+		return Move{}, errors.New("bad slot in " + strconv.Quote(token))
+	}
+	return Move{EventType: PLAY_CARD, Target: target, Card: c}, nil
+}
+
+// FormatMoves writes a history as numbered turns.
+func FormatMoves(history []Move) string {
+	tokens := []string{}
+	turn := 0
+	startOfTurn := true
+	for _, m := range history {
+		if startOfTurn {
+			turn += 1
+			tokens = append(tokens, strconv.Itoa(turn)+".")
+		}
+		tokens = append(tokens, m.Notation())
+		startOfTurn = m.EventType == PLAY_CARD
+	}
+	return strings.Join(tokens, " ")
+}
+
+const (
+	RESULT_P1_WIN   = "1-0"
+	RESULT_P2_WIN   = "0-1"
+	RESULT_DRAW     = "1/2-1/2"
+	RESULT_UNKNOWN  = "*"
+	RESULT_FINISHED = "-" // solo games have no winner
+)
+
+func ResultString(s GameState) string {
+	switch s {
+	case P1_WIN:
+		return RESULT_P1_WIN
+	case P2_WIN:
+		return RESULT_P2_WIN
+	case DRAW:
+		return RESULT_DRAW
+	case FINISHED:
+		return RESULT_FINISHED
+	}
+	return RESULT_UNKNOWN
+}
+
+// ArchivedGame is a game record together with the headers describing it.
+type ArchivedGame struct {
+	Players [2]string
+	Agents  [2]string // human or the name of an agent
+	Record  GameRecord
+	Result  string
+	Scores  [2]int
+	Extra   [][2]string // headers this package does not know, kept in order
+
+	hasScores bool
+}
+
+// NewArchivedGame describes a game that has been played on g.
+func NewArchivedGame(g *Game, players, agents [2]string) *ArchivedGame {
+	return &ArchivedGame{
+		Players: players,
+		Agents:  agents,
+		Record:  g.Record(),
+		Result:  ResultString(g.State),
+		Scores:  [2]int{g.Pyramid1.Score(), g.Pyramid2.Score()},
+	}
+}
+
+const ARCHIVE_LINE_WIDTH = 80
+
+func (a *ArchivedGame) Headers() [][2]string {
+	headers := [][2]string{
+		{"P1", a.Players[0]},
+		{"P2", a.Players[1]},
+		{"P1Agent", a.Agents[0]},
+		{"P2Agent", a.Agents[1]},
+		{"Seed", strconv.FormatInt(a.Record.Seed, 10)},
+		{"Variant", a.Record.Rules.Name},
+		{"Result", a.Result},
+		{"Score", strconv.Itoa(a.Scores[0]) + "-" + strconv.Itoa(a.Scores[1])},
+	}
+	if a.Record.Start != "" {
+		headers = append(headers, [2]string{"Position", a.Record.Start})
+	}
+	if a.Record.Solo {
+		headers = append(headers, [2]string{"Mode", "solo"})
+	}
+	return append(headers, a.Extra...)
+}
+
+// String writes the game in archive format, wrapping the moves to
+// ARCHIVE_LINE_WIDTH.
+func (a *ArchivedGame) String() string {
+	var sb strings.Builder
+	for _, h := range a.Headers() {
+		fmt.Fprintf(&sb, "[%s %s]\n", h[0], strconv.Quote(h[1]))
+	}
+	sb.WriteString("\n")
+	line := 0
+	tokens := strings.Fields(FormatMoves(a.Record.History))
+	for _, t := range append(tokens, a.Result) {
+		if line > 0 && line+1+len(t) > ARCHIVE_LINE_WIDTH {
+			sb.WriteString("\n")
+			line = 0
+		} else if line > 0 {
+			sb.WriteString(" ")
+			line += 1
+		}
+		sb.WriteString(t)
+		line += len(t)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func WriteArchive(w io.Writer, games []*ArchivedGame) error {
+	for i, a := range games {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, a.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseHeader(line string) (string, string, error) {
+	inner, ok := strings.CutSuffix(strings.TrimPrefix(line, "["), "]")
+	if !ok {
+		return "", "", errors.New("bad header " + line)
+	}
+	name, quoted, ok := strings.Cut(inner, " ")
+	if !ok {
+		return "", "", errors.New("bad header " + line)
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(quoted))
+	if err != nil {
+		return "", "", errors.New("bad header value in " + line)
+	}
+	return name, value, nil
+}
+
+func parseScores(s string) ([2]int, error) {
+	var scores [2]int
+	a, b, ok := strings.Cut(s, "-")
+	if !ok {
+		return scores, errors.New("bad score " + strconv.Quote(s))
+	}
+	var err1, err2 error
+	scores[0], err1 = strconv.Atoi(a)
+	scores[1], err2 = strconv.Atoi(b)
+	if err1 != nil || err2 != nil {
+		return scores, errors.New("bad score " + strconv.Quote(s))
+	}
+	return scores, nil
+}
+
+func (a *ArchivedGame) setHeader(name, value string) error {
+	var err error
+	switch name {
+	case "P1":
+		a.Players[0] = value
+	case "P2":
+		a.Players[1] = value
+	case "P1Agent":
+		a.Agents[0] = value
+	case "P2Agent":
+		a.Agents[1] = value
+	case "Seed":
+		a.Record.Seed, err = strconv.ParseInt(value, 10, 64)
+	case "Variant":
+		a.Record.Rules, err = RulesByName(value)
+	case "Result":
+		a.Result = value
+	case "Score":
+		a.Scores, err = parseScores(value)
+		a.hasScores = true
+	case "Position":
+		a.Record.Start = value
+	case "Mode":
+		a.Record.Solo = value == "solo"
+	default:
+		a.Extra = append(a.Extra, [2]string{name, value})
+	}
+	return err
+}
+
+// setMoves replays the moves on the game's deal, so every drawn card has to
+// match the seed and every placement has to be legal.
+func (a *ArchivedGame) setMoves(tokens []string) error {
+	g, err := a.Record.NewGame()
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if strings.HasSuffix(t, ".") {
+			continue
+		}
+		m, err := ParseMove(t)
+		if err != nil {
+			return err
+		}
+		c, err := g.ApplyMove(AgentEvent{EventType: m.EventType, Target: m.Target})
+		if err != nil {
+			return err
+		}
+		if !sameCard(c, m.Card) {
+			return errors.New(t + " does not match the game, the card was " + c.String())
+		}
+	}
+	a.Record.History = g.History
+	if g.State != IN_PROGRESS {
+		if a.Result != ResultString(g.State) {
+			return errors.New("result " + a.Result + " does not match the moves")
+		}
+		scores := [2]int{g.Pyramid1.Score(), g.Pyramid2.Score()}
+		if a.hasScores && a.Scores != scores {
+			return errors.New("score does not match the moves")
+		}
+		a.Scores = scores
+	}
+	return nil
+}
+
+func isResult(token string) bool {
+	switch token {
+	case RESULT_P1_WIN, RESULT_P2_WIN, RESULT_DRAW, RESULT_UNKNOWN, RESULT_FINISHED:
+		return true
+	}
+	return false
+}
+
+// ReadArchive parses every game in an archive. Games without a Variant header
+// use the standard rules. A game ends at its result, or where a header follows
+// its moves, a blank line after its headers or one of its own headers, so games
+// cut off before the result are still read.
+func ReadArchive(r io.Reader) ([]*ArchivedGame, error) {
+	games := []*ArchivedGame{}
+	scanner := bufio.NewScanner(r)
+	var current *ArchivedGame
+	var tokens []string
+	blank := false // a blank line since the last header
+	seen := map[string]bool{}
+	lineNumber := 0
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		if err := current.setMoves(tokens); err != nil {
+			return fmt.Errorf("game %d: %w", len(games)+1, err)
+		}
+		games = append(games, current)
+		current, tokens, blank = nil, nil, false
+		clear(seen)
+		return nil
+	}
+	for scanner.Scan() {
+		lineNumber += 1
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			blank = current != nil
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name, value, err := parseHeader(line)
+			if err != nil {
+				return games, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			if current != nil && (tokens != nil || blank || seen[name]) {
+				if err := finish(); err != nil {
+					return games, err
+				}
+			}
+			if current == nil {
+				current = &ArchivedGame{Record: GameRecord{Rules: StandardRules}, Result: RESULT_UNKNOWN}
+			}
+			if err := current.setHeader(name, value); err != nil {
+				return games, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			blank, seen[name] = false, true
+			continue
+		}
+		if current == nil {
+			return games, fmt.Errorf("line %d: moves before any headers", lineNumber)
+		}
+		for _, t := range strings.Fields(line) {
+			if isResult(t) {
+				current.Result = t
+				if tokens == nil {
+					tokens = []string{}
+				}
+				if err := finish(); err != nil {
+					return games, err
+				}
+				break
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return games, err
+	}
+	return games, finish()
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// partGame plays moves moves of a sample game on seed.
+func partGame(t *testing.T, seed int64, moves int) *Game {
+	t.Helper()
+	g := NewVariantGame(seed, StandardRules)
+	agents := sampleAgents(t, seed)
+	for len(g.History) < moves {
+		a := agents[g.CurrentPlayer()]
+		a.SetVisibleCard(g.TopDiscard())
+		if _, err := ApplyAndNotify(g, agents, a.GenerateMove()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	names, agents := [2]string{"Ann", "Bo"}, [2]string{"sample", "sample"}
+	games := []*ArchivedGame{
+		NewArchivedGame(playSampleGame(t, 1), names, agents),
+		NewArchivedGame(partGame(t, 2, 7), names, agents),
+		NewArchivedGame(NewVariantGame(3, StandardRules), names, agents),
+		NewArchivedGame(playSampleGame(t, 4), names, agents),
+	}
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, games); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadArchive(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(games) {
+		t.Fatalf("read %d games back, wrote %d", len(read), len(games))
+	}
+	for i, a := range games {
+		b := read[i]
+		if b.Record.Seed != a.Record.Seed || b.Result != a.Result || b.Players != a.Players || b.Scores != a.Scores {
+			t.Errorf("game %d read back as seed %d %s %v %v, wrote seed %d %s %v %v", i+1,
+				b.Record.Seed, b.Result, b.Players, b.Scores, a.Record.Seed, a.Result, a.Players, a.Scores)
+		}
+		if FormatMoves(b.Record.History) != FormatMoves(a.Record.History) {
+			t.Errorf("game %d read back as %s, wrote %s", i+1, FormatMoves(b.Record.History), FormatMoves(a.Record.History))
+		}
+	}
+}
+
+func TestReadArchiveWithoutResult(t *testing.T) {
+	// games cut off before the result line, one with its moves and one
+	// with only its headers
+	part := NewArchivedGame(partGame(t, 5, 4), [2]string{"Ann", "Bo"}, [2]string{"human", "model"})
+	empty := NewArchivedGame(NewVariantGame(6, StandardRules), [2]string{"Ann", "Bo"}, [2]string{"human", "model"})
+	last := NewArchivedGame(playSampleGame(t, 7), [2]string{"Ann", "Bo"}, [2]string{"sample", "sample"})
+	headers, _, _ := strings.Cut(empty.String(), "\n\n")
+	text := strings.TrimSuffix(part.String(), " *\n") + "\n" + headers + "\n" + last.String()
+	for _, text := range []string{text, strings.ReplaceAll(text, "\n[P1 ", "\n\n[P1 ")} {
+		read, err := ReadArchive(strings.NewReader(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(read) != 3 {
+			t.Fatalf("read %d games from\n%s", len(read), text)
+		}
+		for i, a := range []*ArchivedGame{part, empty, last} {
+			if read[i].Record.Seed != a.Record.Seed || len(read[i].Record.History) != len(a.Record.History) {
+				t.Errorf("game %d read back as seed %d with %d moves, wrote seed %d with %d", i+1,
+					read[i].Record.Seed, len(read[i].Record.History), a.Record.Seed, len(a.Record.History))
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package core
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+A position string describes a game in one line, with six fields separated by
+spaces:
+
+	4p5y--------/6p--------- 7y4y ? 3 2 standard
+
+  - both pyramids separated by a slash, each written as its ten slots in order
+    with a card code or - for an empty slot
+  - the discard stack from the bottom up, or - if it is empty
+  - the deck from the top down, - if it is empty or ? if the order is unknown
+  - the turn, counting from 1
+  - the draws left this turn
+  - the rules variant, which can be left out for the standard rules
+
+When the deck is unknown it is made of every card not seen elsewhere, shuffled
+with the seed the game is created with.
+*/
+
+const UNKNOWN_DECK = "?"
+
+func writeCards(sb *strings.Builder, cards []*Card) {
+	if len(cards) == 0 {
+		sb.WriteString("-")
+	}
+	for _, c := range cards {
+		sb.WriteString(c.String())
+	}
+}
+
+func writePyramid(sb *strings.Builder, p *Pyramid) {
+	for _, c := range p.Cards {
+		if c == nil {
+			sb.WriteString("-")
+		} else {
+			sb.WriteString(c.String())
+		}
+	}
+}
+
+func (g *Game) position(deck bool) string {
+	var sb strings.Builder
+	writePyramid(&sb, g.Pyramid1)
+	sb.WriteString("/")
+	writePyramid(&sb, g.Pyramid2)
+	sb.WriteString(" ")
+	writeCards(&sb, g.Discards)
+	sb.WriteString(" ")
+	if deck {
+		writeCards(&sb, g.Deck)
+	} else {
+		sb.WriteString(UNKNOWN_DECK)
+	}
+	sb.WriteString(" " + strconv.Itoa(g.Turn+1) + " " + strconv.Itoa(g.DrawsLeft) + " " + g.Rules.Name)
+	return sb.String()
+}
+
+// Position writes the whole game, including the order of the deck.
+func (g *Game) Position() string {
+	return g.position(true)
+}
+
+// PublicPosition writes the game as the players see it, with the deck
+// unknown.
+func (g *Game) PublicPosition() string {
+	return g.position(false)
+}
+
+func parseCards(s string) ([]*Card, error) {
+	cards := []*Card{}
+	if s == "-" {
+		return cards, nil
+	}
+	if len(s)%2 != 0 {
+		return nil, errors.New("bad card list " + strconv.Quote(s))
+	}
+	for i := 0; i < len(s); i += 2 {
+		c, err := ParseCard(s[i : i+2])
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	return cards, nil
+}
+
+func parsePyramid(s string) (*Pyramid, error) {
+	p := &Pyramid{}
+	slot := 0
+	for i := 0; i < len(s); slot++ {
+		if slot == 10 {
+			return nil, errors.New("too many slots in " + strconv.Quote(s))
+		}
+		if s[i] == '-' {
+			i += 1
+			continue
+		}
+		if i+2 > len(s) {
+			return nil, errors.New("bad pyramid " + strconv.Quote(s))
+		}
+		c, err := ParseCard(s[i : i+2])
+		if err != nil {
+			return nil, err
+		}
+		p.Cards[slot] = c
+		i += 2
+	}
+	if slot != 10 {
+		return nil, errors.New("a pyramid needs ten slots, got " + strconv.Itoa(slot))
+	}
+	// the slots above the bottom row can only be filled once the slots they
+	// rest on are
+	for i := 6; i < 10; i++ {
+		if p.Cards[i] != nil {
+			c := p.Cards[i]
+			p.Cards[i] = nil
+			ok := p.CanPlace(i)
+			p.Cards[i] = c
+			if !ok {
+				return nil, errors.New("slot " + strconv.Itoa(i) + " is filled before the slots below it")
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *Pyramid) Count() int {
+	n := 0
+	for _, c := range p.Cards {
+		if c != nil {
+			n += 1
+		}
+	}
+	return n
+}
+
+// NewGameFromPosition sets up a game from a position string. The seed shuffles
+// an unknown deck and seeds the game's random number generator.
+func NewGameFromPosition(position string, seed int64) (*Game, error) {
+	fields := strings.Fields(position)
+	if len(fields) != 5 && len(fields) != 6 {
+		return nil, errors.New("a position has five or six fields, got " + strconv.Itoa(len(fields)))
+	}
+	rules := StandardRules
+	if len(fields) == 6 {
+		var err error
+		if rules, err = RulesByName(fields[5]); err != nil {
+			return nil, err
+		}
+	}
+	g := NewVariantGame(seed, rules)
+
+	p1, p2, ok := strings.Cut(fields[0], "/")
+	if !ok {
+		return nil, errors.New("the pyramids must be separated by /")
+	}
+	var err error
+	if g.Pyramid1, err = parsePyramid(p1); err != nil {
+		return nil, err
+	}
+	if g.Pyramid2, err = parsePyramid(p2); err != nil {
+		return nil, err
+	}
+	if g.Discards, err = parseCards(fields[1]); err != nil {
+		return nil, err
+	}
+	turn, err := strconv.Atoi(fields[3])
+	if err != nil || turn < 1 || turn > 21 {
+		return nil, errors.New("bad turn " + strconv.Quote(fields[3]))
+	}
+	g.Turn = turn - 1
+	if g.Pyramid1.Count() != (g.Turn+1)/2 || g.Pyramid2.Count() != g.Turn/2 {
+		return nil, errors.New("the number of cards placed does not match turn " + fields[3])
+	}
+	g.DrawsLeft, err = strconv.Atoi(fields[4])
+	if err != nil || g.DrawsLeft < 0 || g.DrawsLeft > rules.DrawsPerTurn {
+		return nil, errors.New("bad draws left " + strconv.Quote(fields[4]))
+	}
+
+	// give each card a copy number, the same way the deck numbers them
+	var copies [20]int
+	number := func(cards []*Card) error {
+		for _, c := range cards {
+			if c == nil {
+				continue
+			}
+			t := TypeIndex(c)
+			if copies[t] == 2 {
+				return errors.New("more than two copies of " + c.String())
+			}
+			c.Copy = copies[t]
+			copies[t] += 1
+		}
+		return nil
+	}
+	for _, cards := range [][]*Card{g.Pyramid1.Cards[:], g.Pyramid2.Cards[:], g.Discards} {
+		if err := number(cards); err != nil {
+			return nil, err
+		}
+	}
+	if fields[2] == UNKNOWN_DECK {
+		g.Deck = []*Card{}
+		for _, c := range NewDeck() {
+			if c.Copy >= copies[TypeIndex(c)] {
+				g.Deck = append(g.Deck, c)
+			}
+		}
+		g.Rand.Shuffle(len(g.Deck), func(i, j int) { g.Deck[i], g.Deck[j] = g.Deck[j], g.Deck[i] })
+	} else {
+		if g.Deck, err = parseCards(fields[2]); err != nil {
+			return nil, err
+		}
+		if err := number(g.Deck); err != nil {
+			return nil, err
+		}
+	}
+	g.Discards = append(make([]*Card, 0, 40), g.Discards...)
+	g.Start = position
+	g.checkGameOver()
+	if g.State == IN_PROGRESS && !g.HasLegalMove() {
+		return nil, errors.New("the player to move has no legal move")
+	}
+	return g, nil
+}
@@ -0,0 +1,42 @@
+package core
+
+import "testing"
+
+func TestSampleAgentJoinsPosition(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		g, err := NewGameFromPosition("---4p------/-----5y---- - ? 3 2", seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		agents := sampleAgents(t, seed)
+		SetPosition(g, agents)
+		if err := RunGame(g, agents); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+	}
+}
+
+func TestPositionWithoutLegalMove(t *testing.T) {
+	if _, err := NewGameFromPosition("----------/---------- - - 1 2", 0); err == nil {
+		t.Error("a position with nothing to draw or place was accepted")
+	}
+}
+
+func TestReplaySoloGameFromPosition(t *testing.T) {
+	g, err := NewGameFromPosition("----------/---------- - ? 1 2", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Solo = true
+	agents := sampleAgents(t, 7)
+	if err := RunGame(g, agents); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := g.Record().Replay(len(g.History))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replayed.Solo || replayed.State != FINISHED || replayed.Pyramid1.Score() != g.Pyramid1.Score() {
+		t.Error("the solo game did not replay the same")
+	}
+}
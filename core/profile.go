@@ -0,0 +1,223 @@
+package core
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prizelobby/pyramid-rummy/storage"
+)
+
+var PROFILES_KEY = storage.Key{Namespace: "stats", Name: "profiles", Version: 1}
+
+// the opponent of a solo game, and of a human seat played against another
+const SOLO_OPPONENT = "solo"
+const HUMAN_OPPONENT = "human"
+
+// MATCH_HISTORY_LENGTH is how many recent matches a profile keeps.
+const MATCH_HISTORY_LENGTH = 30
+
+// MAX_NAME_LENGTH is the longest player name, in characters.
+const MAX_NAME_LENGTH = 16
+
+func DefaultPlayerName(seat int) string {
+	if seat == 0 {
+		return "Player 1"
+	}
+	return "Player 2"
+}
+
+// MatchResult is one finished game from the side of one player.
+type MatchResult struct {
+	Date     string `json:"date"`
+	Opponent string `json:"opponent"` // the agent, human or solo
+	Score    int    `json:"score"`
+	OppScore int    `json:"opp_score"` // the target score in a solo game
+	Outcome  string `json:"outcome"`   // win, draw or loss
+	Edges    [6]int `json:"edges"`     // the score of each edge of the player's pyramid
+	Code     string `json:"code"`      // share code of the game
+}
+
+// NewMatchResult records g from the side of the player in seat. A solo game
+// is won by reaching SOLO_TARGET.
+func NewMatchResult(g *Game, seat int, opponent string, now time.Time) MatchResult {
+	code, _ := ShareCode(g.Record())
+	p, opp := g.Pyramid1, g.Pyramid2
+	if seat == 1 {
+		p, opp = opp, p
+	}
+	m := MatchResult{Date: DailyDate(now), Opponent: opponent, Score: p.Score(), Code: code}
+	if g.Solo {
+		m.Opponent, m.OppScore = SOLO_OPPONENT, SOLO_TARGET
+	} else {
+		m.OppScore = opp.Score()
+	}
+	for i := range 6 {
+		m.Edges[i], _ = p.EdgeScore(i)
+	}
+	switch {
+	case m.Score > m.OppScore || g.Solo && m.Score == m.OppScore:
+		m.Outcome = "win"
+	case m.Score == m.OppScore:
+		m.Outcome = "draw"
+	default:
+		m.Outcome = "loss"
+	}
+	return m
+}
+
+// Record totals the matches against one kind of opponent.
+type Record struct {
+	Games      int    `json:"games"`
+	Wins       int    `json:"wins"`
+	Draws      int    `json:"draws"`
+	Losses     int    `json:"losses"`
+	TotalScore int    `json:"total_score"`
+	BestScore  int    `json:"best_score"`
+	EdgeScored [6]int `json:"edge_scored"` // matches in which each edge scored
+}
+
+func (r *Record) Add(m MatchResult) {
+	r.Games += 1
+	switch m.Outcome {
+	case "win":
+		r.Wins += 1
+	case "draw":
+		r.Draws += 1
+	default:
+		r.Losses += 1
+	}
+	r.TotalScore += m.Score
+	r.BestScore = max(r.BestScore, m.Score)
+	for i, s := range m.Edges {
+		if s > 0 {
+			r.EdgeScored[i] += 1
+		}
+	}
+}
+
+// Merge adds the totals of o to r.
+func (r *Record) Merge(o *Record) {
+	r.Games += o.Games
+	r.Wins += o.Wins
+	r.Draws += o.Draws
+	r.Losses += o.Losses
+	r.TotalScore += o.TotalScore
+	r.BestScore = max(r.BestScore, o.BestScore)
+	for i := range 6 {
+		r.EdgeScored[i] += o.EdgeScored[i]
+	}
+}
+
+func (r *Record) AverageScore() float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return float64(r.TotalScore) / float64(r.Games)
+}
+
+// TopEdges lists the edges that have scored, most often first.
+func (r *Record) TopEdges() []int {
+	edges := []int{}
+	for i, n := range r.EdgeScored {
+		if n > 0 {
+			edges = append(edges, i)
+		}
+	}
+	sort.SliceStable(edges, func(a, b int) bool {
+		return r.EdgeScored[edges[a]] > r.EdgeScored[edges[b]]
+	})
+	return edges
+}
+
+// Profile is a player on this machine, with their results by opponent and
+// their most recent matches, oldest first.
+type Profile struct {
+	Name    string             `json:"name"`
+	Records map[string]*Record `json:"records"`
+	Recent  []MatchResult      `json:"recent"`
+}
+
+func (p *Profile) Add(m MatchResult) {
+	if p.Records == nil {
+		p.Records = map[string]*Record{}
+	}
+	r, ok := p.Records[m.Opponent]
+	if !ok {
+		r = &Record{}
+		p.Records[m.Opponent] = r
+	}
+	r.Add(m)
+	p.Recent = append(p.Recent, m)
+	if len(p.Recent) > MATCH_HISTORY_LENGTH {
+		p.Recent = p.Recent[len(p.Recent)-MATCH_HISTORY_LENGTH:]
+	}
+}
+
+// Total combines the records against every opponent, leaving out solo games
+// since they aren't played against anyone.
+func (p *Profile) Total() *Record {
+	total := &Record{}
+	for opponent, r := range p.Records {
+		if opponent != SOLO_OPPONENT {
+			total.Merge(r)
+		}
+	}
+	return total
+}
+
+// Opponents lists the opponents the player has records against, with the
+// agents weakest first, then other humans, then solo games.
+func (p *Profile) Opponents() []string {
+	order := []string{"random", "sample", "model", HUMAN_OPPONENT, SOLO_OPPONENT}
+	opponents := []string{}
+	for _, o := range order {
+		if _, ok := p.Records[o]; ok {
+			opponents = append(opponents, o)
+		}
+	}
+	return opponents
+}
+
+type Profiles struct {
+	Players []*Profile `json:"players"`
+	Seats   [2]string  `json:"seats"` // the names last picked for each seat
+}
+
+func LoadProfiles() (*Profiles, error) {
+	p := &Profiles{}
+	err := PROFILES_KEY.Load(p)
+	return p, err
+}
+
+func (p *Profiles) Save() error {
+	return PROFILES_KEY.Save(p)
+}
+
+// Get returns the profile called name, adding it if there isn't one.
+func (p *Profiles) Get(name string) *Profile {
+	for _, pr := range p.Players {
+		if pr.Name == name {
+			return pr
+		}
+	}
+	pr := &Profile{Name: name, Records: map[string]*Record{}}
+	p.Players = append(p.Players, pr)
+	return pr
+}
+
+// Names lists the profiles by name, in the order they were added.
+func (p *Profiles) Names() []string {
+	names := make([]string, len(p.Players))
+	for i, pr := range p.Players {
+		names[i] = pr.Name
+	}
+	return names
+}
+
+// SeatName is the name last picked for seat, or the default one.
+func (p *Profiles) SeatName(seat int) string {
+	if p.Seats[seat] != "" {
+		return p.Seats[seat]
+	}
+	return DefaultPlayerName(seat)
+}
@@ -0,0 +1,514 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prizelobby/pyramid-rummy/storage"
+)
+
+/*
+A puzzle is a position where one action is clearly better than the rest. The
+position is public, so the deck is unknown, and the actions are scored by
+playing the game out many times from each of them with the deck shuffled
+differently every time and model agents in both seats. Every action is played
+out on the same shuffles, which keeps the comparison between them fair.
+
+Puzzles are kept in a JSON file so they can be generated, checked and edited
+by hand before they ship.
+*/
+
+const (
+	PUZZLE_EASY   = "easy"
+	PUZZLE_MEDIUM = "medium"
+	PUZZLE_HARD   = "hard"
+)
+
+var PuzzleDifficulties = []string{PUZZLE_EASY, PUZZLE_MEDIUM, PUZZLE_HARD}
+
+// PUZZLE_MIN_GAP is how many points the best action has to be ahead of the
+// next one, and PUZZLE_Z how many standard errors the gap has to be, for a
+// position to count as a puzzle.
+const PUZZLE_MIN_GAP = 2.0
+const PUZZLE_Z = 3.0
+
+// PUZZLE_CHECK_SEED seeds a second solve of every new puzzle, on shuffles far
+// from the ones it was found with, and PUZZLE_CHECK_MARGIN is how much further
+// ahead the answer has to be there. Puzzles near the line are left out rather
+// than failing the next check.
+const PUZZLE_CHECK_SEED = 1 << 32
+const PUZZLE_CHECK_MARGIN = 1.0
+
+var PUZZLE_PROGRESS_KEY = storage.Key{Namespace: "progress", Name: "puzzles", Version: 1}
+
+type PuzzleValue struct {
+	Action string  `json:"action"`
+	Value  float64 `json:"value"` // expected final margin for the player to move
+}
+
+type Puzzle struct {
+	ID         string        `json:"id"`
+	Position   string        `json:"position"`
+	Answer     string        `json:"answer"`
+	Difficulty string        `json:"difficulty"`
+	Gap        float64       `json:"gap"`
+	Values     []PuzzleValue `json:"values"`
+	Note       string        `json:"note,omitempty"`
+}
+
+// PuzzleID names a puzzle after its position, so the same position always
+// gets the same ID.
+func PuzzleID(position string) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(position)))
+}
+
+// ParseAction reads an action written by AgentEvent.String.
+func ParseAction(s string) (AgentEvent, error) {
+	if s == "draw" {
+		return AgentEvent{EventType: DRAW_CARDS}, nil
+	}
+	if slot, ok := strings.CutPrefix(s, "play "); ok {
+		n, err := strconv.Atoi(slot)
+		if err == nil && n >= 0 && n < 10 {
+			return AgentEvent{EventType: PLAY_CARD, Target: n}, nil
+		}
+	}
+	return AgentEvent{}, errors.New("bad action " + strconv.Quote(s))
+}
+
+// Value returns the value of action, or false if the puzzle has no value for
+// it.
+func (p *Puzzle) Value(action AgentEvent) (float64, bool) {
+	for _, v := range p.Values {
+		if v.Action == action.String() {
+			return v.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Game sets up the puzzle position. The seed shuffles the unknown deck.
+func (p *Puzzle) Game(seed int64) (*Game, error) {
+	return NewGameFromPosition(p.Position, seed)
+}
+
+func legalActions(g *Game) []AgentEvent {
+	actions := []AgentEvent{}
+	if e := (AgentEvent{EventType: DRAW_CARDS}); g.CanMove(e) {
+		actions = append(actions, e)
+	}
+	for i := range 10 {
+		if e := (AgentEvent{EventType: PLAY_CARD, Target: i}); g.CanMove(e) {
+			actions = append(actions, e)
+		}
+	}
+	return actions
+}
+
+// playOut finishes the game with model agents in both seats and returns the
+// final margin for player.
+func playOut(g *Game, model *LinearModel, player int) (float64, error) {
+	agents := [2]GameAgent{NewModelAgent(0, model), NewModelAgent(1, model)}
+	SetPosition(g, agents)
+	if err := RunGame(g, agents); err != nil {
+		return 0, err
+	}
+	margin := g.Pyramid1.Score() - g.Pyramid2.Score()
+	if player == 1 {
+		margin = -margin
+	}
+	return float64(margin), nil
+}
+
+// Solution is the result of solving a position: every legal action with its
+// value, best first, and the margins of each play out.
+type Solution struct {
+	Values  []PuzzleValue
+	margins [][]float64
+}
+
+// SolvePosition scores every legal action in position by playing the game out
+// samples times from each.
+func SolvePosition(position string, samples int, seed int64, model *LinearModel) (*Solution, error) {
+	g, err := NewGameFromPosition(position, seed)
+	if err != nil {
+		return nil, err
+	}
+	if g.State != IN_PROGRESS {
+		return nil, errors.New("the game is over in this position")
+	}
+	player := g.CurrentPlayer()
+	actions := legalActions(g)
+	s := &Solution{Values: make([]PuzzleValue, len(actions)), margins: make([][]float64, len(actions))}
+	for i, a := range actions {
+		total := 0.0
+		for j := range samples {
+			g, err := NewGameFromPosition(position, seed+int64(j))
+			if err != nil {
+				return nil, err
+			}
+			if _, err := g.ApplyMove(a); err != nil {
+				return nil, err
+			}
+			m, err := playOut(g, model, player)
+			if err != nil {
+				return nil, err
+			}
+			total += m
+			s.margins[i] = append(s.margins[i], m)
+		}
+		s.Values[i] = PuzzleValue{Action: a.String(), Value: total / float64(max(samples, 1))}
+	}
+	sort.Sort(s)
+	return s, nil
+}
+
+func (s *Solution) Len() int           { return len(s.Values) }
+func (s *Solution) Less(i, j int) bool { return s.Values[i].Value > s.Values[j].Value }
+func (s *Solution) Swap(i, j int) {
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+	s.margins[i], s.margins[j] = s.margins[j], s.margins[i]
+}
+
+// Gap returns how far the best action is ahead of the second best and the
+// standard error of that gap. Both play outs of a sample share the shuffle,
+// so the error comes from the difference sample by sample.
+func (s *Solution) Gap() (float64, float64) {
+	if len(s.Values) < 2 {
+		return 0, 0
+	}
+	n := len(s.margins[0])
+	if n < 2 {
+		return s.Values[0].Value - s.Values[1].Value, math.Inf(1)
+	}
+	mean := 0.0
+	for k := range n {
+		mean += s.margins[0][k] - s.margins[1][k]
+	}
+	mean /= float64(n)
+	variance := 0.0
+	for k := range n {
+		d := s.margins[0][k] - s.margins[1][k] - mean
+		variance += d * d
+	}
+	variance /= float64(n - 1)
+	return mean, math.Sqrt(variance / float64(n))
+}
+
+// Clear reports whether the best action is far enough ahead to make a puzzle.
+func (s *Solution) Clear() bool {
+	return s.clearBy(PUZZLE_MIN_GAP)
+}
+
+func (s *Solution) clearBy(minGap float64) bool {
+	gap, stderr := s.Gap()
+	return gap >= minGap && gap >= PUZZLE_Z*stderr
+}
+
+// obviousAction is what a beginner would play: the slot that scores the most
+// points right away, or a draw if no slot scores anything. ok is false if more
+// than one slot scores the most.
+func obviousAction(g *Game) (AgentEvent, bool) {
+	p := g.Pyramid1
+	if g.CurrentPlayer() == 1 {
+		p = g.Pyramid2
+	}
+	score := p.Score()
+	best, bestGain, ties := AgentEvent{EventType: DRAW_CARDS}, 0, 0
+	for _, a := range legalActions(g) {
+		if a.EventType != PLAY_CARD {
+			continue
+		}
+		gain := p.TentativeScoreWithCard(g.TopDiscard(), a.Target) - score
+		if gain > bestGain {
+			best, bestGain, ties = a, gain, 1
+		} else if gain == bestGain && gain > 0 {
+			ties += 1
+		}
+	}
+	if bestGain == 0 && !g.CanMove(best) {
+		return best, false
+	}
+	return best, ties <= 1
+}
+
+// GradePuzzle sets the difficulty by who finds the answer. A puzzle is easy if
+// taking the most points right away is the answer, medium if the hint model
+// finds it and hard otherwise.
+func GradePuzzle(g *Game, answer AgentEvent, model *LinearModel) string {
+	if obvious, ok := obviousAction(g); ok && obvious == answer {
+		return PUZZLE_EASY
+	}
+	if best, ok := BestAction(model.ActionValues(StateFromGame(g))); ok && best.Event == answer {
+		return PUZZLE_MEDIUM
+	}
+	return PUZZLE_HARD
+}
+
+// NewPuzzle solves position and returns it as a puzzle, or nil if no action is
+// clearly best. The answer also has to hold when the position is solved again
+// with PUZZLE_CHECK_SEED, so a lucky set of shuffles doesn't make a puzzle.
+func NewPuzzle(position string, samples int, seed int64, model *LinearModel) (*Puzzle, error) {
+	s, err := SolvePosition(position, samples, seed, model)
+	if err != nil {
+		return nil, err
+	}
+	return NewPuzzleFromSolution(position, s, samples, seed, model)
+}
+
+// NewPuzzleFromSolution is NewPuzzle for a position that has already been
+// solved with samples and seed, so it isn't solved again.
+func NewPuzzleFromSolution(position string, s *Solution, samples int, seed int64, model *LinearModel) (*Puzzle, error) {
+	if !s.Clear() {
+		return nil, nil
+	}
+	g, err := NewGameFromPosition(position, seed)
+	if err != nil {
+		return nil, err
+	}
+	answer, err := ParseAction(s.Values[0].Action)
+	if err != nil {
+		return nil, err
+	}
+	gap, _ := s.Gap()
+	p := &Puzzle{
+		ID:         PuzzleID(position),
+		Position:   position,
+		Answer:     s.Values[0].Action,
+		Difficulty: GradePuzzle(g, answer, DefaultModel),
+		Gap:        math.Round(gap*10) / 10,
+		Values:     roundValues(s.Values),
+	}
+	if checkPuzzle(p, samples, PUZZLE_CHECK_SEED, model, PUZZLE_MIN_GAP+PUZZLE_CHECK_MARGIN) != nil {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func roundValues(values []PuzzleValue) []PuzzleValue {
+	rounded := make([]PuzzleValue, len(values))
+	for i, v := range values {
+		rounded[i] = PuzzleValue{Action: v.Action, Value: math.Round(v.Value*10) / 10}
+	}
+	return rounded
+}
+
+type PuzzleConfig struct {
+	Seed       int64 // game i is dealt with Seed+i
+	Games      int
+	Samples    int // play outs per action
+	Rules      Rules
+	Model      *LinearModel
+	Workers    int
+	Difficulty string // only keep puzzles this hard, any if empty
+}
+
+// PUZZLE_SCREEN_SAMPLES is how many play outs per action a position gets to
+// guess its difficulty before it is solved properly.
+const PUZZLE_SCREEN_SAMPLES = 25
+
+// screenDifficulty guesses the difficulty position would have as a puzzle from
+// a quick solve, so positions that won't have the difficulty being looked for
+// can be skipped without solving them in full.
+func screenDifficulty(position string, seed int64, model *LinearModel) (string, error) {
+	s, err := SolvePosition(position, PUZZLE_SCREEN_SAMPLES, seed, model)
+	if err != nil {
+		return "", err
+	}
+	g, err := NewGameFromPosition(position, seed)
+	if err != nil {
+		return "", err
+	}
+	answer, err := ParseAction(s.Values[0].Action)
+	if err != nil {
+		return "", err
+	}
+	return GradePuzzle(g, answer, DefaultModel), nil
+}
+
+// candidatePosition plays a model game and returns the public position at a
+// decision picked at random from the middle of the game, where there are
+// enough cards down for the edges to matter and more than one action. With
+// placeOnly the position is taken after the last draw of a turn, so the puzzle
+// is only about where the card goes.
+func candidatePosition(seed int64, rules Rules, model *LinearModel, placeOnly bool) (string, error) {
+	g := NewVariantGame(seed, rules)
+	agents := [2]GameAgent{NewModelAgent(0, model), NewModelAgent(1, model)}
+	r := rand.New(rand.NewSource(seed))
+	stop := 4 + r.Intn(12)
+	for g.State == IN_PROGRESS {
+		placing := g.DrawsLeft == 0 || len(g.Deck) == 0
+		if g.Turn >= stop && len(g.Discards) > 0 && len(legalActions(g)) > 1 && (placing || !placeOnly) {
+			return g.PublicPosition(), nil
+		}
+		current := agents[g.CurrentPlayer()]
+		current.SetVisibleCard(g.TopDiscard())
+		if _, err := ApplyAndNotify(g, agents, current.GenerateMove()); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// GeneratePuzzles looks for one puzzle in each of config.Games games, across
+// config.Workers goroutines. Games without a clear best action, or whose best
+// action doesn't hold up when checked, are skipped, as are puzzles of another
+// difficulty than config.Difficulty.
+// Every other game only looks at placements, since draws are the better move
+// in most positions that allow them.
+func GeneratePuzzles(config PuzzleConfig) ([]*Puzzle, error) {
+	if config.Rules.Name == "" {
+		config.Rules = StandardRules
+	}
+	if config.Model == nil {
+		config.Model = DefaultModel
+	}
+	puzzles := make([]*Puzzle, config.Games)
+	errs := make([]error, config.Games)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(config.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				seed := config.Seed + int64(i)
+				position, err := candidatePosition(seed, config.Rules, config.Model, i%2 == 0)
+				if err != nil || position == "" {
+					errs[i] = err
+					continue
+				}
+				if config.Difficulty != "" {
+					d, err := screenDifficulty(position, seed, config.Model)
+					if err != nil || d != config.Difficulty {
+						errs[i] = err
+						continue
+					}
+				}
+				p, err := NewPuzzle(position, config.Samples, seed, config.Model)
+				if p != nil && config.Difficulty != "" && p.Difficulty != config.Difficulty {
+					p = nil
+				}
+				puzzles[i], errs[i] = p, err
+			}
+		}()
+	}
+	for i := range config.Games {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	found := []*Puzzle{}
+	for i, p := range puzzles {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if p != nil {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+// CheckPuzzle solves the puzzle again and reports what is wrong with it, or
+// nil if the answer still holds.
+func CheckPuzzle(p *Puzzle, samples int, seed int64, model *LinearModel) error {
+	return checkPuzzle(p, samples, seed, model, PUZZLE_MIN_GAP)
+}
+
+func checkPuzzle(p *Puzzle, samples int, seed int64, model *LinearModel, minGap float64) error {
+	if _, err := ParseAction(p.Answer); err != nil {
+		return err
+	}
+	s, err := SolvePosition(p.Position, samples, seed, model)
+	if err != nil {
+		return err
+	}
+	if s.Values[0].Action != p.Answer {
+		return errors.New("the best action is now " + s.Values[0].Action + ", not " + p.Answer)
+	}
+	if !s.clearBy(minGap) {
+		gap, _ := s.Gap()
+		return fmt.Errorf("%s is only %.1f ahead", p.Answer, gap)
+	}
+	return nil
+}
+
+func ParsePuzzles(data []byte) ([]*Puzzle, error) {
+	puzzles := []*Puzzle{}
+	if err := json.Unmarshal(data, &puzzles); err != nil {
+		return nil, err
+	}
+	for _, p := range puzzles {
+		if _, err := NewGameFromPosition(p.Position, 0); err != nil {
+			return nil, errors.New("puzzle " + p.ID + ": " + err.Error())
+		}
+		if _, err := ParseAction(p.Answer); err != nil {
+			return nil, errors.New("puzzle " + p.ID + ": " + err.Error())
+		}
+	}
+	return puzzles, nil
+}
+
+func LoadPuzzles(path string) ([]*Puzzle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePuzzles(data)
+}
+
+// SavePuzzles writes the puzzles ordered by difficulty, easiest first.
+func SavePuzzles(path string, puzzles []*Puzzle) error {
+	rank := map[string]int{}
+	for i, d := range PuzzleDifficulties {
+		rank[d] = i
+	}
+	sort.SliceStable(puzzles, func(i, j int) bool {
+		return rank[puzzles[i].Difficulty] < rank[puzzles[j].Difficulty]
+	})
+	data, err := json.MarshalIndent(puzzles, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// PuzzleProgress records which puzzles have been solved on this machine.
+type PuzzleProgress struct {
+	Solved map[string]bool `json:"solved"`
+}
+
+func LoadPuzzleProgress() (*PuzzleProgress, error) {
+	p := &PuzzleProgress{}
+	err := PUZZLE_PROGRESS_KEY.Load(p)
+	if p.Solved == nil {
+		p.Solved = map[string]bool{}
+	}
+	return p, err
+}
+
+func (p *PuzzleProgress) Save() error {
+	return PUZZLE_PROGRESS_KEY.Save(p)
+}
+
+// Next returns the index of the first unsolved puzzle, starting over if every
+// puzzle has been solved.
+func (p *PuzzleProgress) Next(puzzles []*Puzzle) int {
+	for i, pz := range puzzles {
+		if !p.Solved[pz.ID] {
+			return i
+		}
+	}
+	return 0
+}
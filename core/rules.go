@@ -0,0 +1,35 @@
+package core
+
+import (
+	"errors"
+	"sort"
+)
+
+// Rules are the settings that differ between variants of the game.
+type Rules struct {
+	Name         string
+	DrawsPerTurn int
+}
+
+var StandardRules = Rules{Name: "standard", DrawsPerTurn: 2}
+
+var Variants = map[string]Rules{
+	"standard": StandardRules,
+	"single":   {Name: "single", DrawsPerTurn: 1},
+}
+
+func VariantNames() []string {
+	names := make([]string, 0, len(Variants))
+	for n := range Variants {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func RulesByName(name string) (Rules, error) {
+	if r, ok := Variants[name]; ok {
+		return r, nil
+	}
+	return Rules{}, errors.New("unknown rules variant " + name)
+}
@@ -0,0 +1,35 @@
+package core
+
+// RunGame plays g to completion with the given agents, keeping both agents
+// informed of every draw and placement. It stops if an agent makes an illegal
+// move.
+func RunGame(g *Game, agents [2]GameAgent) error {
+	for g.State == IN_PROGRESS {
+		current := agents[g.CurrentPlayer()]
+		current.SetVisibleCard(g.TopDiscard())
+		if _, err := ApplyAndNotify(g, agents, current.GenerateMove()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyAndNotify makes a move for the player to move and tells the agents
+// about it. Seats without an agent (human players) are nil.
+func ApplyAndNotify(g *Game, agents [2]GameAgent, e AgentEvent) (*Card, error) {
+	player := g.CurrentPlayer()
+	c, err := g.ApplyMove(e)
+	if err != nil {
+		return nil, err
+	}
+	if e.EventType == DRAW_CARDS {
+		for _, a := range agents {
+			if a != nil {
+				a.RevealCard(c)
+			}
+		}
+	} else if other := agents[1-player]; other != nil {
+		other.AcceptMove(c, e.Target)
+	}
+	return c, nil
+}
@@ -0,0 +1,48 @@
+package core
+
+import "github.com/prizelobby/pyramid-rummy/storage"
+
+var SAVED_GAME_KEY = storage.Key{Namespace: "game", Name: "saved", Version: 1}
+
+// SavedGame is a game left part way through, kept so it can be picked up
+// again. There is only ever one.
+type SavedGame struct {
+	Code    string    `json:"code"`    // share code of the moves so far
+	Agents  [2]string `json:"agents"`  // the agent in each seat, empty for a human
+	Players [2]string `json:"players"` // the profile of each human seat
+	Daily   string    `json:"daily,omitempty"`
+}
+
+// NewSavedGame saves g with agents and players in the seats. Games set up from
+// a position can't be saved since share codes can't hold them.
+func NewSavedGame(g *Game, agents, players [2]string, daily string) (*SavedGame, error) {
+	code, err := ShareCode(g.Record())
+	if err != nil {
+		return nil, err
+	}
+	return &SavedGame{Code: code, Agents: agents, Players: players, Daily: daily}, nil
+}
+
+// LoadSavedGame returns the saved game, or nil if there isn't one.
+func LoadSavedGame() (*SavedGame, error) {
+	var s *SavedGame
+	err := SAVED_GAME_KEY.Load(&s)
+	return s, err
+}
+
+func (s *SavedGame) Save() error {
+	return SAVED_GAME_KEY.Save(s)
+}
+
+func ClearSavedGame() error {
+	return SAVED_GAME_KEY.Delete()
+}
+
+// Game plays the saved moves again from the deal.
+func (s *SavedGame) Game() (*Game, error) {
+	r, err := ParseShareCode(s.Code)
+	if err != nil {
+		return nil, err
+	}
+	return r.Replay(len(r.History))
+}
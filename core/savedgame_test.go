@@ -0,0 +1,58 @@
+package core
+
+import "testing"
+
+func sampleAgents(t *testing.T, seed int64) [2]GameAgent {
+	t.Helper()
+	var agents [2]GameAgent
+	for p := range 2 {
+		a, err := NewAgent("sample", p, seed*2+int64(p), StandardRules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		agents[p] = a
+	}
+	return agents
+}
+
+// movesBefore counts the moves made before turn (counted from 1) starts.
+func movesBefore(history []Move, turn int) int {
+	played := 0
+	for i, m := range history {
+		if played == turn-1 {
+			return i
+		}
+		if m.EventType == PLAY_CARD {
+			played += 1
+		}
+	}
+	return len(history)
+}
+
+func TestResumeSavedGame(t *testing.T) {
+	for seed := int64(0); seed < 40; seed++ {
+		full := NewVariantGame(seed, StandardRules)
+		if err := RunGame(full, sampleAgents(t, seed)); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		for _, turn := range []int{2, 3, 4, 11, 18} {
+			g, err := full.Record().Replay(movesBefore(full.History, turn))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := NewSavedGame(g, [2]string{"sample", "sample"}, [2]string{}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resumed, err := s.Game()
+			if err != nil {
+				t.Fatalf("seed %d turn %d: %v", seed, turn, err)
+			}
+			agents := sampleAgents(t, seed+1000)
+			SetPosition(resumed, agents)
+			if err := RunGame(resumed, agents); err != nil {
+				t.Errorf("seed %d resumed at turn %d: %v", seed, turn, err)
+			}
+		}
+	}
+}
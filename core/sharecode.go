@@ -0,0 +1,133 @@
+package core
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strings"
+)
+
+/*
+A share code packs a game into a short string that can be pasted in chat. The
+bytes are
+
+	version, variant, seed (varint), number of moves, moves, checksum
+
+with two moves to a byte, a draw written as SHARE_DRAW and a placement as its
+slot. Solo games set SHARE_SOLO in the variant byte. The cards are not stored
+since the seed deals them again. The bytes are written in base32 without
+padding.
+*/
+
+const SHARE_CODE_VERSION = 1
+const SHARE_DRAW = 0xf
+const SHARE_SOLO = 0x80
+
+// shareVariants numbers the variants for share codes. New variants go at the
+// end so old codes keep working.
+var shareVariants = []string{"standard", "single"}
+
+var shareEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func shareChecksum(b []byte) byte {
+	return byte(crc32.ChecksumIEEE(b))
+}
+
+// ShareCode writes the game as a share code. Games set up from a position
+// can't be shared this way since the seed alone does not deal them.
+func ShareCode(r GameRecord) (string, error) {
+	if r.Start != "" {
+		return "", errors.New("games started from a position have no share code")
+	}
+	variant := -1
+	for i, name := range shareVariants {
+		if name == r.Rules.Name {
+			variant = i
+		}
+	}
+	if variant == -1 {
+		return "", errors.New("the " + r.Rules.Name + " variant has no share code")
+	}
+	if len(r.History) > 255 {
+		return "", errors.New("too many moves for a share code")
+	}
+
+	if r.Solo {
+		variant |= SHARE_SOLO
+	}
+	b := []byte{SHARE_CODE_VERSION, byte(variant)}
+	b = binary.AppendVarint(b, r.Seed)
+	b = append(b, byte(len(r.History)))
+	for i, m := range r.History {
+		nibble := byte(m.Target)
+		if m.EventType == DRAW_CARDS {
+			nibble = SHARE_DRAW
+		}
+		if i%2 == 0 {
+			b = append(b, nibble<<4)
+		} else {
+			b[len(b)-1] |= nibble
+		}
+	}
+	b = append(b, shareChecksum(b))
+	return shareEncoding.EncodeToString(b), nil
+}
+
+// ParseShareCode reads a share code back into the game it was made from.
+// Case, spaces and dashes are ignored so codes survive being retyped.
+func ParseShareCode(code string) (GameRecord, error) {
+	code = strings.ToUpper(code)
+	code = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '\n' || r == '\t' {
+			return -1
+		}
+		return r
+	}, code)
+	b, err := shareEncoding.DecodeString(code)
+	if err != nil || len(b) < 4 {
+		return GameRecord{}, errors.New("not a game code")
+	}
+	if shareChecksum(b[:len(b)-1]) != b[len(b)-1] {
+		return GameRecord{}, errors.New("the game code has a typo")
+	}
+	b = b[:len(b)-1]
+	if b[0] != SHARE_CODE_VERSION {
+		return GameRecord{}, errors.New("the game code is from a newer version")
+	}
+	solo := b[1]&SHARE_SOLO != 0
+	variant := int(b[1] &^ SHARE_SOLO)
+	if variant >= len(shareVariants) {
+		return GameRecord{}, errors.New("the game code has an unknown variant")
+	}
+	rules, err := RulesByName(shareVariants[variant])
+	if err != nil {
+		return GameRecord{}, err
+	}
+	seed, n := binary.Varint(b[2:])
+	if n <= 0 || len(b) < 3+n {
+		return GameRecord{}, errors.New("not a game code")
+	}
+	count := int(b[2+n])
+	moves := b[3+n:]
+	if len(moves) != (count+1)/2 {
+		return GameRecord{}, errors.New("the game code has the wrong number of moves")
+	}
+
+	g := NewVariantGame(seed, rules)
+	g.Solo = solo
+	for i := range count {
+		nibble := moves[i/2] >> 4
+		if i%2 == 1 {
+			nibble = moves[i/2] & 0xf
+		}
+		e := AgentEvent{EventType: PLAY_CARD, Target: int(nibble)}
+		if nibble == SHARE_DRAW {
+			e = AgentEvent{EventType: DRAW_CARDS}
+		}
+		if _, err := g.ApplyMove(e); err != nil {
+			return GameRecord{}, err
+		}
+	}
+	return g.Record(), nil
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"sync"
+)
+
+type SimConfig struct {
+	Games     int
+	Seed      int64 // game i is dealt with Seed+i
+	Agents    [2]string
+	Rules     Rules
+	Model     *LinearModel // weights for model seats
+	Workers   int
+	WithLuck  bool // measure luck and skill for every game, which is slower
+	LuckModel *LinearModel
+}
+
+type GameResult struct {
+	Game    int         `json:"game"`
+	Seed    int64       `json:"seed"`
+	Agents  [2]string   `json:"agents"`
+	Variant string      `json:"variant"`
+	Scores  [2]int      `json:"scores"`
+	Outcome string      `json:"outcome"` // p1, p2 or draw
+	Moves   int         `json:"moves"`
+	Luck    *LuckReport `json:"luck,omitempty"`
+}
+
+type SimSummary struct {
+	Games     int        `json:"games"`
+	Wins      [2]int     `json:"wins"`
+	Draws     int        `json:"draws"`
+	AvgScore  [2]float64 `json:"avg_score"`
+	AvgSkill  [2]float64 `json:"avg_skill"`
+	AvgLuckP1 float64    `json:"avg_luck_p1,omitempty"`
+}
+
+func OutcomeName(s GameState) string {
+	switch s {
+	case P1_WIN:
+		return "p1"
+	case P2_WIN:
+		return "p2"
+	case DRAW:
+		return "draw"
+	case FINISHED:
+		return "finished"
+	}
+	return "in_progress"
+}
+
+// SimulateGame plays game i of the configuration. Agents are seeded from the
+// deal so the same configuration always produces the same games.
+func SimulateGame(config SimConfig, i int) (GameResult, error) {
+	seed := config.Seed + int64(i)
+	g := NewVariantGame(seed, config.Rules)
+	var agents [2]GameAgent
+	for p := range 2 {
+		a, err := NewAgent(config.Agents[p], p, seed*2+int64(p), config.Rules)
+		if err != nil {
+			return GameResult{}, err
+		}
+		if ma, ok := a.(*ModelAgent); ok && config.Model != nil {
+			ma.Model = config.Model
+		}
+		agents[p] = a
+	}
+	if err := RunGame(g, agents); err != nil {
+		return GameResult{}, err
+	}
+
+	r := GameResult{
+		Game:    i,
+		Seed:    seed,
+		Agents:  config.Agents,
+		Variant: config.Rules.Name,
+		Scores:  [2]int{g.Pyramid1.Score(), g.Pyramid2.Score()},
+		Outcome: OutcomeName(g.State),
+		Moves:   len(g.History),
+	}
+	if config.WithLuck {
+		model := config.LuckModel
+		if model == nil {
+			model = DefaultModel
+		}
+		luck, err := MeasureLuck(g.Record(), model)
+		if err != nil {
+			return r, err
+		}
+		r.Luck = luck
+	}
+	return r, nil
+}
+
+// Simulate plays every game of the configuration across config.Workers
+// goroutines. Results are returned in game order.
+func Simulate(config SimConfig) ([]GameResult, error) {
+	if config.Rules.Name == "" {
+		config.Rules = StandardRules
+	}
+	workers := max(config.Workers, 1)
+	results := make([]GameResult, config.Games)
+	errs := make([]error, config.Games)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = SimulateGame(config, i)
+			}
+		}()
+	}
+	for i := range config.Games {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func Summarize(results []GameResult) SimSummary {
+	s := SimSummary{Games: len(results)}
+	if len(results) == 0 {
+		return s
+	}
+	n := float64(len(results))
+	for _, r := range results {
+		switch r.Outcome {
+		case "p1":
+			s.Wins[0] += 1
+		case "p2":
+			s.Wins[1] += 1
+		default:
+			s.Draws += 1
+		}
+		for p := range 2 {
+			s.AvgScore[p] += float64(r.Scores[p]) / n
+			if r.Luck != nil {
+				s.AvgSkill[p] += float64(r.Luck.Skill[p]) / n
+			}
+		}
+		if r.Luck != nil {
+			s.AvgLuckP1 += float64(r.Luck.Luck[0]) / n
+		}
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/prizelobby/pyramid-rummy/storage"
+)
+
+// SOLO_TARGET is the score a solo game is played against, a little above what
+// the model agent averages on its own.
+const SOLO_TARGET = 45
+
+var SOLO_STATS_KEY = storage.Key{Namespace: "stats", Name: "solo", Version: 1}
+
+// NewSoloGame deals a game where one player fills Pyramid1 over ten turns
+// with the usual draws.
+func NewSoloGame(seed int64, rules Rules) *Game {
+	g := NewVariantGame(seed, rules)
+	g.Solo = true
+	return g
+}
+
+// SoloRating compares a solo score with the best pyramid that could have been
+// built from the cards the player was offered.
+type SoloRating struct {
+	Score   int     `json:"score"`
+	Best    int     `json:"best"`
+	Percent float64 `json:"percent"`
+}
+
+func RateSoloGame(r GameRecord) (*SoloRating, error) {
+	if !r.Solo {
+		return nil, errors.New("not a solo game")
+	}
+	offers, err := OfferedCards(r)
+	if err != nil {
+		return nil, err
+	}
+	best, _, err := BestPyramid(offers[0])
+	if err != nil {
+		return nil, err
+	}
+	g, err := r.Replay(len(r.History))
+	if err != nil {
+		return nil, err
+	}
+	rating := &SoloRating{Score: g.Pyramid1.Score(), Best: best, Percent: 100}
+	if best > 0 {
+		rating.Percent = 100 * float64(rating.Score) / float64(best)
+	}
+	return rating, nil
+}
+
+// SoloStats are the personal records for solo games on this machine.
+type SoloStats struct {
+	Games       int     `json:"games"`
+	TotalScore  int     `json:"total_score"`
+	BestScore   int     `json:"best_score"`
+	BestPercent float64 `json:"best_percent"`
+}
+
+func LoadSoloStats() (*SoloStats, error) {
+	s := &SoloStats{}
+	err := SOLO_STATS_KEY.Load(s)
+	return s, err
+}
+
+func (s *SoloStats) Save() error {
+	return SOLO_STATS_KEY.Save(s)
+}
+
+// Add counts a finished game and reports whether it set a new best score.
+func (s *SoloStats) Add(r *SoloRating) bool {
+	newBest := s.Games > 0 && r.Score > s.BestScore
+	s.Games += 1
+	s.TotalScore += r.Score
+	s.BestScore = max(s.BestScore, r.Score)
+	s.BestPercent = max(s.BestPercent, r.Percent)
+	return newBest
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+type TrainConfig struct {
+	Generations int
+	Games       int // self-play games per generation
+	Seed        int64
+	Epsilon     float64
+	Ridge       float64
+}
+
+type GenerationResult struct {
+	Generation int
+	Samples    int
+	MSE        float64
+	Model      *LinearModel
+}
+
+type trainingSample struct {
+	Features [NUM_FEATURES]float64
+	Player   int
+}
+
+// recordingAgent wraps a ModelAgent and keeps the features of every position it
+// placed a card into, so they can be labelled with the final margin.
+type recordingAgent struct {
+	*ModelAgent
+	samples *[]trainingSample
+}
+
+func (r *recordingAgent) GenerateMove() AgentEvent {
+	s := r.State()
+	gift := s.Gift()
+	m := r.ModelAgent.GenerateMove()
+	if m.EventType == PLAY_CARD {
+		own := s.Own.Cards
+		f := MarginFeatures(&own, &s.Opp.Cards, gift, &s.Unseen)
+		*r.samples = append(*r.samples, trainingSample{Features: f, Player: r.PlayerNumber})
+	}
+	return m
+}
+
+// SelfPlayGames plays games between two copies of model and returns the
+// features of every placement labelled with that player's final margin.
+func SelfPlayGames(model *LinearModel, games int, epsilon float64, r *rand.Rand) ([][NUM_FEATURES]float64, []float64, error) {
+	xs := [][NUM_FEATURES]float64{}
+	ys := []float64{}
+	for range games {
+		g := NewSeededGame(r.Int63())
+		samples := []trainingSample{}
+		var agents [2]GameAgent
+		for p := range 2 {
+			a := NewModelAgent(p, model)
+			a.Rand = rand.New(rand.NewSource(r.Int63()))
+			a.Epsilon = epsilon
+			agents[p] = &recordingAgent{ModelAgent: a, samples: &samples}
+		}
+		if err := RunGame(g, agents); err != nil {
+			return nil, nil, err
+		}
+		margin := float64(g.Pyramid1.Score() - g.Pyramid2.Score())
+		for _, s := range samples {
+			xs = append(xs, s.Features)
+			if s.Player == 0 {
+				ys = append(ys, margin)
+			} else {
+				ys = append(ys, -margin)
+			}
+		}
+	}
+	return xs, ys, nil
+}
+
+// FitLinearModel solves the ridge regression of ys on xs.
+func FitLinearModel(xs [][NUM_FEATURES]float64, ys []float64, ridge float64) (*LinearModel, error) {
+	const n = NUM_FEATURES
+	var a [n][n + 1]float64
+	for k, x := range xs {
+		for i := range n {
+			for j := range n {
+				a[i][j] += x[i] * x[j]
+			}
+			a[i][n] += x[i] * ys[k]
+		}
+	}
+	for i := 1; i < n; i++ {
+		a[i][i] += ridge
+	}
+
+	// gaussian elimination with partial pivoting
+	for col := range n {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		if math.Abs(a[col][col]) < 1e-9 {
+			// a feature that never varies, leave its weight at zero
+			a[col] = [n + 1]float64{}
+			a[col][col] = 1
+		}
+		for row := range n {
+			if row == col {
+				continue
+			}
+			factor := a[row][col] / a[col][col]
+			for k := col; k <= n; k++ {
+				a[row][k] -= factor * a[col][k]
+			}
+		}
+	}
+
+	m := &LinearModel{}
+	for i := range n {
+		m.Weights[i] = a[i][n] / a[i][i]
+		if math.IsNaN(m.Weights[i]) || math.IsInf(m.Weights[i], 0) {
+			return nil, errors.New("training diverged")
+		}
+	}
+	return m, nil
+}
+
+func meanSquaredError(m *LinearModel, xs [][NUM_FEATURES]float64, ys []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	total := 0.0
+	for i, x := range xs {
+		d := m.Evaluate(x) - ys[i]
+		total += d * d
+	}
+	return total / float64(len(xs))
+}
+
+// Train improves start by repeated self-play, calling progress after each
+// generation. Each generation plays against the model fit by the previous one.
+func Train(start *LinearModel, config TrainConfig, progress func(GenerationResult)) (*LinearModel, error) {
+	r := rand.New(rand.NewSource(config.Seed))
+	model := start
+	for gen := range config.Generations {
+		xs, ys, err := SelfPlayGames(model, config.Games, config.Epsilon, r)
+		if err != nil {
+			return model, err
+		}
+		next, err := FitLinearModel(xs, ys, config.Ridge)
+		if err != nil {
+			return model, err
+		}
+		model = next
+		if progress != nil {
+			progress(GenerationResult{
+				Generation: gen + 1,
+				Samples:    len(xs),
+				MSE:        meanSquaredError(model, xs, ys),
+				Model:      model,
+			})
+		}
+	}
+	return model, nil
+}
@@ -2,7 +2,10 @@ module github.com/prizelobby/pyramid-rummy
 
 go 1.22
 
-require github.com/hajimehoshi/ebiten/v2 v2.7.5
+require (
+	github.com/atotto/clipboard v0.1.4
+	github.com/hajimehoshi/ebiten/v2 v2.7.5
+)
 
 require (
 	github.com/ebitengine/gomobile v0.0.0-20240518074828-e86332849895 // indirect
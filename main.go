@@ -1,16 +1,13 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"runtime"
-	"strconv"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"github.com/prizelobby/pyramid-rummy/core"
 	"github.com/prizelobby/pyramid-rummy/res"
 	"github.com/prizelobby/pyramid-rummy/scene"
 	"github.com/prizelobby/pyramid-rummy/ui"
@@ -34,10 +31,11 @@ type EbitenGame struct {
 	ScaledScreen *ui.ScaledScreen
 	gameState    GameState
 	SceneManager *scene.SceneManager
+	Menu         *scene.MenuScene
 }
 
 func (g *EbitenGame) Update() error {
-	if inpututil.IsKeyJustPressed(ebiten.KeyQ) && runtime.GOOS != "js" {
+	if inpututil.IsKeyJustPressed(ebiten.KeyQ) && runtime.GOOS != "js" && !g.Menu.Typing() {
 		os.Exit(0)
 	}
 
@@ -69,59 +67,25 @@ func main() {
 
 	args := os.Args[1:]
 
-	if len(args) > 0 {
-		if args[0] == "agenttest" {
-			iterations := 10
-			if len(args) > 1 {
-				var err error
-				iterations, err = strconv.Atoi(args[1])
-				if err != nil {
-					fmt.Println("unable to parse iterations, defaulting to 10")
-				}
-			}
-			p1Wins := 0
-			p2Wins := 0
-			p1TotalScore := 0
-			p2TotalScore := 0
-			draws := 0
-			for i := range iterations {
-				game := core.NewGame()
-				a1 := core.NewSampleAgent(0)
-				a2 := core.NewSampleAgent(1)
-				a2.Strategy = 1
-				var currentAgent core.GameAgent = a1
-				var otherAgent core.GameAgent = a2
-				for game.State == core.IN_PROGRESS {
-					currentAgent.SetVisibleCard(game.TopDiscard())
-					m := currentAgent.GenerateMove()
-					if m.EventType == core.DRAW_CARDS {
-						c := game.DrawCard()
-						currentAgent.RevealCard(c)
-						otherAgent.RevealCard(c)
-					} else if m.EventType == core.PLAY_CARD {
-						t := m.Target
-						game.PlayCard(t)
-						otherAgent.AcceptMove(game.TopDiscard(), t)
-						currentAgent, otherAgent = otherAgent, currentAgent
-					}
-				}
-				fmt.Printf("Game %d\n", i)
-				if game.State == core.P1_WIN {
-					fmt.Println("p1 win")
-					p1Wins += 1
-				} else if game.State == core.P2_WIN {
-					fmt.Println("p2 win")
-					p2Wins += 1
-				} else {
-					fmt.Println("draw")
-					draws += 1
-				}
-				p1TotalScore += game.Pyramid1.Score()
-				p2TotalScore += game.Pyramid2.Score()
-				fmt.Printf("Score %d - %d\n", game.Pyramid1.Score(), game.Pyramid2.Score())
-			}
-			fmt.Printf("Results %d %d %d\n", p1Wins, p2Wins, draws)
-			fmt.Printf("Avg scores %.2f %.2f\n", float64(p1TotalScore)/float64(iterations), float64(p2TotalScore)/float64(iterations))
+	// a share code or archive file to open in the replay viewer instead of
+	// starting at the menu, from "open CODE" or the page address on the web
+	startCode := gameCodeFromURL()
+	if len(args) > 1 && args[0] == "open" {
+		startCode = args[1]
+	} else if len(args) > 0 {
+		switch args[0] {
+		case "agenttest":
+			agentTest(args[1:])
+		case "train":
+			train(args[1:])
+		case "dataset":
+			dataset(args[1:])
+		case "simulate":
+			simulate(args[1:])
+		case "play":
+			play(args[1:])
+		case "puzzles":
+			puzzles(args[1:])
 		}
 		os.Exit(0)
 	}
@@ -142,6 +106,10 @@ func main() {
 		gameState:    MENU,
 	}
 
+	if err := scene.LoadSettings(); err != nil {
+		log.Println(err)
+	}
+
 	sm := scene.NewSceneManager()
 	menuScene := scene.NewMenuScene(audioContext)
 	creditsScene := scene.NewCreditsScene()
@@ -150,7 +118,17 @@ func main() {
 	sm.AddScene("credits", creditsScene)
 
 	g.SceneManager = sm
+	g.Menu = menuScene
 	sm.SwitchToScene("menu")
+	if startCode != "" {
+		open := menuScene.OpenGameCode
+		if _, err := os.Stat(startCode); err == nil {
+			open = menuScene.OpenArchive
+		}
+		if err := open(startCode); err != nil {
+			log.Println(err)
+		}
+	}
 
 	ebiten.SetWindowSize(GAME_WIDTH, GAME_HEIGHT)
 	ebiten.SetWindowTitle("Rummy Pyramid")
@@ -23,7 +23,7 @@ import (
 	"embed"
 )
 
-//go:embed font/* img/*.png audio/* shader/*
+//go:embed font/* img/*.png audio/* shader/* data/*
 var assets embed.FS
 
 var fonts map[string]*sfnt.Font = make(map[string]*sfnt.Font)
@@ -106,6 +106,11 @@ func GetImage(p string) *ebiten.Image {
 	return eimg
 }
 
+// ReadData returns a data file, such as the puzzle set.
+func ReadData(fileName string) ([]byte, error) {
+	return assets.ReadFile(path.Join("data", fileName))
+}
+
 func DecodeWavToBytes(audioContext *audio.Context, fileName string) []byte {
 	data, err := assets.ReadFile(path.Join("audio", fileName))
 	if err != nil {
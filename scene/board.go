@@ -0,0 +1,93 @@
+package scene
+
+import (
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/ui"
+)
+
+// BoardLayout is where GameScene draws the pyramids, the deck and the
+// revealed card.
+type BoardLayout struct {
+	XLocs, YLocs       [2][10]float64
+	StartX             [2]float64
+	StartY             float64
+	DeckX, DeckY       float64
+	DiscardX, DiscardY float64
+	ButtonX, ButtonY   float64 // center of the first game over button, the rest go below it
+}
+
+var DuelLayout = &BoardLayout{
+	XLocs:    [2][10]float64{P0XLocs, P1XLocs},
+	YLocs:    [2][10]float64{P0YLocs, P1YLocs},
+	StartX:   [2]float64{P0StartX, P1StartX},
+	StartY:   P0StartY,
+	DeckX:    DECK_BUTTON_X,
+	DeckY:    DECK_BUTTON_Y,
+	DiscardX: DISCARD_X,
+	DiscardY: DISCARD_Y,
+	ButtonX:  640,
+	ButtonY:  500,
+}
+
+// SoloLayout centers the one pyramid and moves the deck to its left.
+var SoloLayout = NewSoloLayout()
+
+const SOLO_START_X float64 = 640 - 178
+
+func NewSoloLayout() *BoardLayout {
+	l := &BoardLayout{
+		StartX:   [2]float64{SOLO_START_X, SOLO_START_X},
+		StartY:   P0StartY,
+		DeckX:    120,
+		DeckY:    DECK_BUTTON_Y,
+		DiscardX: 300,
+		DiscardY: DISCARD_Y,
+		ButtonX:  270,
+		ButtonY:  540,
+	}
+	for i := range 10 {
+		l.XLocs[0][i] = P0XLocs[i] - P0StartX + SOLO_START_X
+		l.YLocs[0][i] = P0YLocs[i]
+	}
+	l.XLocs[1], l.YLocs[1] = l.XLocs[0], l.YLocs[0]
+	return l
+}
+
+// PyramidSprites builds resting sprites for every card in p, with the tile
+// faces that GameScene switches to as cards are stacked on top of each other.
+func PyramidSprites(p *core.Pyramid, xs, ys *[10]float64) [10]*ui.CardSprite {
+	var sprites [10]*ui.CardSprite
+	for i, c := range p.Cards {
+		if c == nil {
+			continue
+		}
+		sprites[i] = ui.NewCardSprite(c, xs[i], ys[i]-ui.TILE_HEIGHT)
+		if i >= 6 {
+			sprites[i].ShadowType = 2
+		}
+	}
+
+	has := func(i int) bool { return sprites[i] != nil }
+	if has(6) {
+		sprites[1].DisplayType = ui.DISPLAY_TYPE_LEFT
+		sprites[2].DisplayType = ui.DISPLAY_TYPE_RIGHT
+	}
+	if has(7) {
+		sprites[3].DisplayType = ui.DISPLAY_TYPE_LEFT
+		sprites[4].DisplayType = ui.DISPLAY_TYPE_RIGHT
+	}
+	if has(8) {
+		if has(7) {
+			sprites[2].DisplayType = ui.DISPLAY_TYPE_RIGHT
+			sprites[4].DisplayType = ui.DISPLAY_TYPE_BOTTOM
+		} else {
+			sprites[4].DisplayType = ui.DISPLAY_TYPE_LEFT
+		}
+		sprites[5].DisplayType = ui.DISPLAY_TYPE_RIGHT
+	}
+	if has(9) {
+		sprites[7].DisplayType = ui.DISPLAY_TYPE_LEFT
+		sprites[8].DisplayType = ui.DISPLAY_TYPE_RIGHT
+	}
+	return sprites
+}
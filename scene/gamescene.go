@@ -1,9 +1,12 @@
 package scene
 
 import (
+	"context"
 	"image/color"
+	"log"
 	"math"
 	"strconv"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
@@ -21,6 +24,7 @@ const (
 	WAITING_FOR_OPP_MOVE
 	WAITING_FOR_PLAYER_ANIMIMATION
 	GAME_OVER
+	PUZZLE_DONE
 )
 
 type GameScene struct {
@@ -32,8 +36,12 @@ type GameScene struct {
 	Game         *core.Game
 	SelectedCard *core.Card
 
-	Agents   [2]core.GameAgent
-	moveChan chan core.AgentEvent
+	Agents       [2]core.GameAgent
+	AgentNames   [2]string // how each agent was made, for saving the game
+	PlayerNames  [2]string // the profile each human seat's results are kept under
+	moveChan     chan core.AgentEvent
+	agentCtx     context.Context
+	cancelAgents context.CancelFunc
 
 	PendIndex   int
 	PrevPend    int
@@ -53,6 +61,7 @@ type GameScene struct {
 	HoverTile      *ebiten.Image
 	OutlineTile    *ebiten.Image
 	Shadow         *ebiten.Image
+	Tiles          *ui.Tileset
 
 	ShowRules      bool
 	RulesComponent *ui.RulesComponent
@@ -64,20 +73,53 @@ type GameScene struct {
 
 	HelpText string
 
+	HintModel *core.LinearModel
+	Hint      []core.ActionValue
+	HintsUsed [2]int
+
+	ShowEdges bool // draw the score overlay
+
+	ShowTracker  bool
+	TrackerValue int // the tracker gives the chance of drawing this or more
+
+	HistoryScroll int // turns the move list is scrolled back by
+
+	ShowStack bool // keep the whole revealed stack on show
+
+	Paused       bool
+	PauseMessage string
+
+	Luck     *core.LuckReport
+	LuckErr  error // why there is no luck report
+	luckChan chan luckResult
+
+	Layout *BoardLayout
+
+	SoloStats  *core.SoloStats // personal records from before this game
+	SoloRating *core.SoloRating
+	SoloBest   bool // the game set a new best score
+	soloChan   chan *core.SoloRating
+
+	Tutorial *Tutorial // nil outside the tutorial
+
+	Puzzles        []*core.Puzzle // the puzzle set being played, nil outside puzzle mode
+	PuzzleIndex    int
+	PuzzleProgress *core.PuzzleProgress
+	PuzzleChoice   core.AgentEvent
+
+	Daily       string // date of the daily challenge, empty for other games
+	DailyStreak int
+	DailyKept   bool // false if the day had already been played
+
 	ActionSound []byte
 	SlideSound  []byte
 }
 
-func NewGameScene(p0, p1 int, audioContext *audio.Context) *GameScene {
+// NewGameScene sets up a game between two human players. The other
+// constructors build on it.
+func NewGameScene(audioContext *audio.Context) *GameScene {
 	game := core.NewGame()
-
-	agents := [2]core.GameAgent{nil, nil}
-	if p0 == 1 {
-		agents[0] = core.NewSampleAgent(0)
-	}
-	if p1 == 1 {
-		agents[1] = core.NewSampleAgent(1)
-	}
+	ctx, cancel := context.WithCancel(context.Background())
 
 	return &GameScene{
 		Game:           game,
@@ -85,23 +127,142 @@ func NewGameScene(p0, p1 int, audioContext *audio.Context) *GameScene {
 		HexMap:         res.GetImage("hexmap"),
 		HexMapInactive: res.GetImage("hexmapdeselected"),
 		BaseTile:       res.GetImage("basetile"),
-		HoverTile:      res.GetImage("hexoutlinegreen"),
+		HoverTile:      HoverTile(),
 		OutlineTile:    res.GetImage("hexoutlinebroken"),
 		MapSmall:       res.GetImage("circlemapsmall"),
 		Shadow:         res.GetImage("shadow"),
+		Tiles:          ui.NewTileset(res.GetImage("hextiletileset"), 120, 146),
 		RulesComponent: ui.NewRulesComponent(),
 		moveChan:       make(chan core.AgentEvent, 1),
+		agentCtx:       ctx,
+		cancelAgents:   cancel,
+		luckChan:       make(chan luckResult, 1),
+		soloChan:       make(chan *core.SoloRating, 1),
+		Layout:         DuelLayout,
 		PendIndex:      -1,
+		ShowEdges:      true,
+		ShowTracker:    true,
+		TrackerValue:   6,
 		HelpText:       "Click the deck to reveal a card.",
-		Agents:         agents,
+		PlayerNames:    [2]string{core.DefaultPlayerName(0), core.DefaultPlayerName(1)},
+		HintModel:      core.DefaultModel,
 		ActionSound:    res.DecodeWavToBytes(audioContext, "263002__dermotte__action_02.wav"),
 		SlideSound:     res.DecodeWavToBytes(audioContext, "569705__sheyvan__wood-friction-planks-11.wav"),
 	}
 }
 
+// NewDailyGameScene starts the daily challenge for date, with the player in
+// the first seat.
+func NewDailyGameScene(date string, audioContext *audio.Context) (*GameScene, error) {
+	game, agent, err := core.NewDailyGame(date)
+	if err != nil {
+		return nil, err
+	}
+	g := NewGameScene(audioContext)
+	g.Game = game
+	g.Agents[1] = agent
+	g.AgentNames[1] = core.DAILY_AGENT
+	g.Daily = date
+	return g, nil
+}
+
+// NewSoloGameScene starts a solo game, one pyramid played against the target
+// score and the player's best.
+func NewSoloGameScene(audioContext *audio.Context) *GameScene {
+	g, _ := NewSceneForGame(core.NewSoloGame(time.Now().UnixNano(), core.StandardRules), [2]string{}, [2]string{}, audioContext)
+	return g
+}
+
+// SetGame shows game, which may already be under way, on the board.
+func (g *GameScene) SetGame(game *core.Game) {
+	g.Game = game
+	g.P0Spheres = PyramidSprites(game.Pyramid1, &g.Layout.XLocs[0], &g.Layout.YLocs[0])
+	g.P1Spheres = PyramidSprites(game.Pyramid2, &g.Layout.XLocs[1], &g.Layout.YLocs[1])
+	g.DiscardSprite, g.SecondSprite = nil, nil
+	if n := len(game.Discards); n > 0 {
+		g.DiscardSprite = ui.NewCardSprite(game.Discards[n-1], g.Layout.DiscardX, g.Layout.DiscardY)
+		if n > 1 {
+			g.SecondSprite = ui.NewCardSprite(game.Discards[n-2], g.Layout.DiscardX, g.Layout.DiscardY)
+		}
+	}
+	g.P0Score = game.Pyramid1.Score()
+	g.P1Score = game.Pyramid2.Score()
+	g.CurrentTurn = game.CurrentPlayer()
+	if len(game.Discards) > 0 {
+		g.HelpText = "Drag the open card to your pyramid or click the deck to reveal a new card."
+	}
+}
+
+// startAgentMove lets agent think in the background. Its move is sent on the
+// channel in use when it started, so a move that arrives after the game is
+// restarted or left is dropped rather than played.
+func (g *GameScene) startAgentMove(agent core.GameAgent) {
+	moves, ctx := g.moveChan, g.agentCtx
+	go func() {
+		var m core.AgentEvent
+		if c, ok := agent.(core.CancellableAgent); ok {
+			m = c.GenerateMoveContext(ctx)
+		} else {
+			m = agent.GenerateMove()
+		}
+		if ctx.Err() == nil {
+			moves <- m
+		}
+	}()
+}
+
+// applyAgentMove makes the move an agent sent. An illegal move is logged and
+// replaced by the first legal one, with the agents told the position again so
+// they follow the game rather than the move they asked for.
+func (g *GameScene) applyAgentMove(m core.AgentEvent) (core.AgentEvent, *core.Card, error) {
+	c, err := core.ApplyAndNotify(g.Game, g.Agents, m)
+	if err == nil {
+		return m, c, nil
+	}
+	log.Println("illegal agent move:", err)
+	moves := g.Game.LegalMoves()
+	if len(moves) == 0 {
+		return m, nil, err
+	}
+	m = moves[0]
+	c, err = g.Game.ApplyMove(m)
+	core.SetPosition(g.Game, g.Agents)
+	return m, c, err
+}
+
+// returnDragSprite slides the dragged card back to the discard pile.
+func (g *GameScene) returnDragSprite() {
+	g.DragSprite.ShadowType = 0
+	g.UIState = WAITING_FOR_PLAYER_ANIMIMATION
+	g.AnimationQueue = append(g.AnimationQueue, ui.NewLinearPathAnimator(g.DragSprite, Frames(15),
+		ui.Location{X: g.DragSprite.X, Y: g.DragSprite.Y},
+		ui.Location{X: g.Layout.DiscardX, Y: g.Layout.DiscardY}, ui.EaseOutCubic, func() {
+			g.UIState = WAITING_FOR_PLAYER_MOVE
+		}))
+}
+
+// StopAgents cancels the search of any agent still working on a move, and
+// drops the move of any agent that can't be cancelled.
+func (g *GameScene) StopAgents() {
+	g.cancelAgents()
+	g.agentCtx, g.cancelAgents = context.WithCancel(context.Background())
+	g.moveChan = make(chan core.AgentEvent, 1)
+}
+
+// LastMover is the player who made the latest move.
+func (g *GameScene) LastMover() int {
+	if l := len(g.Game.History); l != 0 {
+		return g.Game.History[l-1].Player
+	}
+	return 0
+}
+
 const RULES_X = 1150
 const RULES_Y = 20
 
+const HINT_X = 1150
+const HINT_Y = 55
+
 const HELPTEXT_Y = 180
 const TURN_TEXT_Y = 90
 
@@ -147,36 +308,59 @@ func (g *GameScene) Draw(screen *ui.ScaledScreen) {
 	}
 
 	screen.DrawText("Show Rules", 18, RULES_X, RULES_Y, color.White)
+	if g.UIState == WAITING_FOR_PLAYER_MOVE && g.Agents[g.Game.CurrentPlayer()] == nil && g.HintsAllowed() {
+		screen.DrawText("Hint (H)", 18, HINT_X, HINT_Y, color.White)
+	}
+	screen.DrawText("Edges (E)", 18, EDGES_X, EDGES_Y, color.White)
+	screen.DrawText("Tracker (T)", 18, EDGES_X, TRACKER_TOGGLE_Y, color.White)
+	if g.ShowTracker {
+		g.DrawTracker(screen)
+	}
 
-	screen.DrawTextCenteredAt(g.HelpText, 32, 640, HELPTEXT_Y, color.White)
-	if g.UIState != GAME_OVER {
-		screen.DrawTextCenteredAt("Player "+strconv.Itoa(g.CurrentTurn+1)+"'s turn", 48, 640, TURN_TEXT_Y, color.White)
+	// the tutorial explains each step beside the pyramid instead
+	if g.Tutorial == nil {
+		screen.DrawTextCenteredAt(g.HelpText, 32, 640, HELPTEXT_Y, color.White)
+	}
+	if g.Tutorial != nil {
+		screen.DrawTextCenteredAt("Tutorial", 48, 640, TURN_TEXT_Y, color.White)
+	} else if g.Puzzles != nil {
+		g.DrawPuzzleStatus(screen)
+	} else if g.Game.Solo {
+		g.DrawSoloStatus(screen)
+	} else if g.UIState != GAME_OVER {
+		screen.DrawTextCenteredAt(g.SeatName(g.CurrentTurn)+"'s turn", 48, 640, TURN_TEXT_Y, color.White)
 	} else {
 		if g.Game.State == core.P1_WIN {
-			screen.DrawTextCenteredAt("P1 Wins", 48, 640, TURN_TEXT_Y, color.White)
+			screen.DrawTextCenteredAt(g.SeatName(0)+" wins", 48, 640, TURN_TEXT_Y, color.White)
 		} else if g.Game.State == core.P2_WIN {
-			screen.DrawTextCenteredAt("P2 Wins", 48, 640, TURN_TEXT_Y, color.White)
+			screen.DrawTextCenteredAt(g.SeatName(1)+" wins", 48, 640, TURN_TEXT_Y, color.White)
 		} else if g.Game.State == core.DRAW {
 			screen.DrawTextCenteredAt("Draw", 48, 640, TURN_TEXT_Y, color.White)
 		}
 	}
 
 	deckOpts := &ebiten.DrawImageOptions{}
-	deckOpts.GeoM.Translate(DECK_BUTTON_X, DECK_BUTTON_Y)
+	deckOpts.GeoM.Translate(g.Layout.DeckX, g.Layout.DeckY)
 	screen.DrawImage(g.BaseTile, deckOpts)
 	deckShadowOpts := &ebiten.DrawImageOptions{}
-	deckShadowOpts.GeoM.Translate(DECK_BUTTON_X, DECK_BUTTON_Y)
+	deckShadowOpts.GeoM.Translate(g.Layout.DeckX, g.Layout.DeckY)
 	screen.DrawImage(g.Shadow, deckShadowOpts)
-	//screen.DrawTextCenteredAt(strconv.Itoa(len(g.Game.Deck))+"\nCards Left", 20, DECK_BUTTON_X+ui.TILE_X_OFFSET/2, DECK_BUTTON_Y+60, color.Black)
+	if g.ShowTracker {
+		screen.DrawTextCenteredAt(strconv.Itoa(len(g.Game.Deck))+"\nCards Left", 20, g.Layout.DeckX+ui.TILE_X_OFFSET/2, g.Layout.DeckY+60, color.Black)
+	}
 
-	screen.DrawTextCenteredAt("Score: "+strconv.Itoa(g.P0Score), 36, P0StartX+ui.TILE_X_OFFSET*1.5, P0StartY-40, color.White)
-	screen.DrawTextCenteredAt("Score: "+strconv.Itoa(g.P1Score), 36, P1StartX+ui.TILE_X_OFFSET*1.5, P1StartY-40, color.White)
+	screen.DrawTextCenteredAt("Score: "+strconv.Itoa(g.P0Score), 36, g.Layout.StartX[0]+ui.TILE_X_OFFSET*1.5, g.Layout.StartY-40, color.White)
+	if !g.Game.Solo {
+		screen.DrawTextCenteredAt("Score: "+strconv.Itoa(g.P1Score), 36, g.Layout.StartX[1]+ui.TILE_X_OFFSET*1.5, g.Layout.StartY-40, color.White)
+	}
 
 	pOpts := &ebiten.DrawImageOptions{}
-	pOpts.GeoM.Translate(P0StartX, P0StartY)
+	pOpts.GeoM.Translate(g.Layout.StartX[0], g.Layout.StartY)
 	pOpts2 := &ebiten.DrawImageOptions{}
-	pOpts2.GeoM.Translate(P1StartX, P0StartY)
-	if g.CurrentTurn == 0 {
+	pOpts2.GeoM.Translate(g.Layout.StartX[1], g.Layout.StartY)
+	if g.Game.Solo {
+		screen.DrawImage(g.HexMap, pOpts)
+	} else if g.CurrentTurn == 0 {
 		screen.DrawImage(g.HexMap, pOpts)
 		screen.DrawImage(g.HexMapInactive, pOpts2)
 	} else {
@@ -184,20 +368,20 @@ func (g *GameScene) Draw(screen *ui.ScaledScreen) {
 		screen.DrawImage(g.HexMap, pOpts2)
 	}
 
-	screen.DrawTextCenteredAt("Revealed:", 30, DISCARD_X+ui.TILE_X_OFFSET/2, DISCARD_Y-50, color.White)
-	screen.DrawTextCenteredAt("Deck:", 30, DECK_BUTTON_X+ui.TILE_X_OFFSET/2, DECK_BUTTON_Y-50, color.White)
-	if g.Agents[g.Game.Turn%2] == nil && g.CurrentTurn == g.Game.Turn%2 {
+	screen.DrawTextCenteredAt("Revealed:", 30, g.Layout.DiscardX+ui.TILE_X_OFFSET/2, g.Layout.DiscardY-50, color.White)
+	screen.DrawTextCenteredAt("Deck:", 30, g.Layout.DeckX+ui.TILE_X_OFFSET/2, g.Layout.DeckY-50, color.White)
+	if g.Agents[g.Game.CurrentPlayer()] == nil && g.CurrentTurn == g.Game.CurrentPlayer() {
 		plural := "s"
 		if g.Game.DrawsLeft == 1 {
 			plural = ""
 		}
-		screen.DrawTextCenteredAt(strconv.Itoa(g.Game.DrawsLeft)+" draw"+plural+" left", 24, DECK_BUTTON_X+ui.TILE_X_OFFSET/2, DECK_BUTTON_Y-20, color.White)
+		screen.DrawTextCenteredAt(strconv.Itoa(g.Game.DrawsLeft)+" draw"+plural+" left", 24, g.Layout.DeckX+ui.TILE_X_OFFSET/2, g.Layout.DeckY-20, color.White)
 	}
 
 	dOpts := &ebiten.DrawImageOptions{}
-	dOpts.GeoM.Translate(DISCARD_X, DISCARD_Y+ui.TILE_HEIGHT)
+	dOpts.GeoM.Translate(g.Layout.DiscardX, g.Layout.DiscardY+ui.TILE_HEIGHT)
 	screen.DrawImage(g.OutlineTile, dOpts)
-	screen.DrawTextCenteredAt("No cards\nin stack", 18, DISCARD_X+ui.TILE_X_OFFSET/2, DISCARD_Y+70, color.White)
+	screen.DrawTextCenteredAt("No cards\nin stack", 18, g.Layout.DiscardX+ui.TILE_X_OFFSET/2, g.Layout.DiscardY+70, color.White)
 
 	if g.SecondSprite != nil {
 		g.SecondSprite.Draw(screen)
@@ -222,13 +406,13 @@ func (g *GameScene) Draw(screen *ui.ScaledScreen) {
 				for j := 6; j < 9; j++ {
 					if g.Game.Pyramid1.CanPlace(j) {
 						outlineOpt := &ebiten.DrawImageOptions{}
-						outlineOpt.GeoM.Translate(P0XLocs[j], P0YLocs[j])
+						outlineOpt.GeoM.Translate(g.Layout.XLocs[0][j], g.Layout.YLocs[0][j])
 						screen.DrawImage(g.OutlineTile, outlineOpt)
 					}
 				}
 				if g.PendIndex > 5 {
 					opt := &ebiten.DrawImageOptions{}
-					opt.GeoM.Translate(P0XLocs[g.PendIndex], P0YLocs[g.PendIndex])
+					opt.GeoM.Translate(g.Layout.XLocs[0][g.PendIndex], g.Layout.YLocs[0][g.PendIndex])
 					screen.DrawImage(g.HoverTile, opt)
 				}
 			}
@@ -243,13 +427,13 @@ func (g *GameScene) Draw(screen *ui.ScaledScreen) {
 				for j := 6; j < 9; j++ {
 					if g.Game.Pyramid2.CanPlace(j) {
 						outlineOpt := &ebiten.DrawImageOptions{}
-						outlineOpt.GeoM.Translate(P1XLocs[j], P1YLocs[j])
+						outlineOpt.GeoM.Translate(g.Layout.XLocs[1][j], g.Layout.YLocs[1][j])
 						screen.DrawImage(g.OutlineTile, outlineOpt)
 					}
 				}
 				if g.PendIndex > 5 {
 					opt := &ebiten.DrawImageOptions{}
-					opt.GeoM.Translate(P1XLocs[g.PendIndex], P1YLocs[g.PendIndex])
+					opt.GeoM.Translate(g.Layout.XLocs[1][g.PendIndex], g.Layout.YLocs[1][g.PendIndex])
 					screen.DrawImage(g.HoverTile, opt)
 				}
 			}
@@ -258,12 +442,12 @@ func (g *GameScene) Draw(screen *ui.ScaledScreen) {
 
 	if g.Game.Pyramid1.CanPlace(9) && g.CurrentTurn == 0 && g.Agents[0] == nil {
 		opt := &ebiten.DrawImageOptions{}
-		opt.GeoM.Translate(P0XLocs[9], P0YLocs[9])
+		opt.GeoM.Translate(g.Layout.XLocs[0][9], g.Layout.YLocs[0][9])
 		screen.DrawImage(g.OutlineTile, opt)
 	}
 	if g.Game.Pyramid2.CanPlace(9) && g.CurrentTurn == 1 && g.Agents[1] == nil {
 		opt := &ebiten.DrawImageOptions{}
-		opt.GeoM.Translate(P1XLocs[9], P1YLocs[9])
+		opt.GeoM.Translate(g.Layout.XLocs[1][9], g.Layout.YLocs[1][9])
 		screen.DrawImage(g.OutlineTile, opt)
 	}
 	if g.PendIndex == 9 {
@@ -273,17 +457,286 @@ func (g *GameScene) Draw(screen *ui.ScaledScreen) {
 		screen.DrawImage(g.HoverTile, opt)
 	}
 
+	if g.ShowEdges {
+		g.DrawScoreOverlay(screen)
+	}
+	if g.Tutorial == nil {
+		g.DrawHistory(screen)
+	}
+	if g.Hint != nil {
+		g.DrawHint(screen)
+	}
+	if g.Tutorial != nil {
+		g.DrawTutorial(screen)
+	}
+
+	if cx, cy := ui.AdjustedCursorPosition(); g.StackShown(cx, cy) {
+		g.DrawStack(screen)
+	}
+
 	if g.DragSprite != nil {
 		g.DragSprite.Draw(screen)
 	}
 
 	if g.UIState == GAME_OVER {
-		screen.DrawUnfilledRect(640-120, 550-20, 240, 40, 2, color.White)
-		screen.DrawTextCenteredAt("Return to menu", 32, 640, 550, color.White)
+		x, y := g.Layout.ButtonX, g.Layout.ButtonY
+		screen.DrawUnfilledRect(x-120, y-20, 240, 40, 2, color.White)
+		screen.DrawTextCenteredAt("Return to menu", 32, x, y, color.White)
+		screen.DrawUnfilledRect(x-120, y+50-20, 240, 40, 2, color.White)
+		screen.DrawTextCenteredAt("Review game", 32, x, y+50, color.White)
+		screen.DrawUnfilledRect(x-120, y+100-20, 240, 40, 2, color.White)
+		screen.DrawTextCenteredAt("Watch replay", 32, x, y+100, color.White)
+		g.DrawSummary(screen)
+	} else if g.UIState == PUZZLE_DONE {
+		g.DrawPuzzleResult(screen)
+	}
+
+	screen.DrawText("Pause (Esc)", 18, PAUSE_X, PAUSE_Y, color.White)
+	if g.Paused {
+		g.DrawPause(screen)
 	}
 
 }
 
+var HintColor = color.RGBA{0xff, 0xe0, 0x66, 0xff}
+
+func formatHintValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+func (g *GameScene) DrawHint(screen *ui.ScaledScreen) {
+	best, _ := core.BestAction(g.Hint)
+	for _, v := range g.Hint {
+		c := color.Color(color.White)
+		if v == best {
+			c = HintColor
+		}
+		if v.Event.EventType == core.DRAW_CARDS {
+			if v == best {
+				screen.DrawUnfilledRect(g.Layout.DeckX-4, g.Layout.DeckY-4, DECK_BUTTON_W+8, DECK_BUTTON_H+8, 3, HintColor)
+			}
+			screen.DrawTextCenteredAt("Draw: "+formatHintValue(v.Value), 24, g.Layout.DeckX+ui.TILE_X_OFFSET/2, g.Layout.DeckY+DECK_BUTTON_H+25, c)
+			continue
+		}
+		_, x, y := g.PyramidXYForTurn(v.Event.Target)
+		if v == best {
+			opt := &ebiten.DrawImageOptions{}
+			opt.GeoM.Translate(x, y)
+			screen.DrawImage(g.HoverTile, opt)
+		}
+		screen.DrawTextCenteredAt(formatHintValue(v.Value), 24, x+ui.TILE_SIZE_X/2, y+(ui.TILE_SIZE_Y-ui.TILE_HEIGHT)/2, c)
+	}
+}
+
+// HintsAllowed is false in puzzles and the tutorial, where a hint would give
+// the answer away.
+func (g *GameScene) HintsAllowed() bool {
+	return g.Puzzles == nil && g.Tutorial == nil
+}
+
+// hintAgentName is the computer player hints come from: the opponent, or the
+// one picked in the settings when there is no computer opponent.
+func (g *GameScene) hintAgentName() string {
+	if name := g.AgentNames[1-g.Game.CurrentPlayer()]; name != "" {
+		return name
+	}
+	return CurrentSettings.Opponent
+}
+
+// HintValues rates every option the current player has, along with what the
+// values mean. Computer players that can't rate options leave it to the hint
+// model. The agent is seeded from the game and the moves so far, so asking
+// again in the same position gives the same hint.
+func (g *GameScene) HintValues() ([]core.ActionValue, string) {
+	name := g.hintAgentName()
+	if name != "model" {
+		seed := g.Game.Seed + int64(len(g.Game.History))
+		a, err := core.NewAgent(name, g.Game.CurrentPlayer(), seed, g.Game.Rules)
+		if e, ok := a.(core.ActionEvaluator); ok && err == nil {
+			core.SetPosition(g.Game, [2]core.GameAgent{a})
+			return g.legalValues(e.ActionValues()), "Hint from " + OPPONENT_LABELS[name] + ": the expected final score of each option."
+		}
+	}
+	values := g.legalValues(g.HintModel.ActionValues(core.StateFromGame(g.Game)))
+	if g.Game.Solo {
+		// the model rates margins, which mean little with no opponent
+		return values, "Hint from " + OPPONENT_LABELS["model"] + ": how highly it rates each option."
+	}
+	return values, "Hint from " + OPPONENT_LABELS["model"] + ": the expected final score margin of each option."
+}
+
+// legalValues drops the values of moves the game doesn't allow, such as a draw
+// from an empty deck, so the hint never points at one.
+func (g *GameScene) legalValues(values []core.ActionValue) []core.ActionValue {
+	legal := []core.ActionValue{}
+	for _, v := range values {
+		if g.Game.CanMove(v.Event) {
+			legal = append(legal, v)
+		}
+	}
+	return legal
+}
+
+// RequestHint shows the value of every option the current player has. The
+// hint stays up until the player acts.
+func (g *GameScene) RequestHint() {
+	if g.Hint != nil {
+		return
+	}
+	g.Hint, g.HelpText = g.HintValues()
+	g.HintsUsed[g.Game.CurrentPlayer()] += 1
+}
+
+// DrawSoloStatus shows the turn count and the scores to beat in place of
+// whose turn it is.
+func (g *GameScene) DrawSoloStatus(screen *ui.ScaledScreen) {
+	best := 0
+	if g.SoloStats != nil {
+		best = g.SoloStats.BestScore
+	}
+	if g.UIState != GAME_OVER {
+		turn := min(g.Game.Turn+1, g.Game.Turns())
+		screen.DrawTextCenteredAt("Turn "+strconv.Itoa(turn)+" of "+strconv.Itoa(g.Game.Turns()), 48, 640, TURN_TEXT_Y, color.White)
+	} else if g.P0Score >= core.SOLO_TARGET {
+		screen.DrawTextCenteredAt("Target beaten", 48, 640, TURN_TEXT_Y, color.White)
+	} else {
+		screen.DrawTextCenteredAt("Finished", 48, 640, TURN_TEXT_Y, color.White)
+	}
+	screen.DrawTextCenteredAt("Target "+strconv.Itoa(core.SOLO_TARGET)+"   Best "+strconv.Itoa(best), 24, 640, TURN_TEXT_Y+45, color.White)
+}
+
+func (g *GameScene) DrawSoloSummary(screen *ui.ScaledScreen) {
+	summary := "Final score " + strconv.Itoa(g.Game.Pyramid1.Score()) + ", hints used: " + strconv.Itoa(g.HintsUsed[0])
+	if r := g.SoloRating; r != nil {
+		summary += "\nBest possible " + strconv.Itoa(r.Best) + ", rating " + strconv.Itoa(int(r.Percent+0.5)) + "%"
+		if g.SoloBest {
+			summary += "\nNew personal best!"
+		}
+	}
+	screen.DrawTextCenteredAt(summary, 24, 1070, 400, color.White)
+}
+
+func (g *GameScene) DrawSummary(screen *ui.ScaledScreen) {
+	if g.Tutorial != nil {
+		return
+	}
+	if g.Game.Solo {
+		g.DrawSoloSummary(screen)
+		return
+	}
+	summary := "Final score " + strconv.Itoa(g.Game.Pyramid1.Score()) + " - " + strconv.Itoa(g.Game.Pyramid2.Score())
+	for p := range 2 {
+		if g.Agents[p] == nil {
+			summary += "\n" + g.SeatName(p) + " hints used: " + strconv.Itoa(g.HintsUsed[p])
+		}
+	}
+	if g.Daily != "" {
+		summary += "\nDaily challenge " + g.Daily + ": " + strconv.Itoa(g.DailyStreak) + " day streak"
+		if !g.DailyKept {
+			summary += " (already played)"
+		}
+	}
+	screen.DrawTextCenteredAt(summary, 24, 640, 665, color.White)
+
+	if g.Luck != nil {
+		for p, x := range []float64{g.Layout.StartX[0], g.Layout.StartX[1]} {
+			text := "Best " + strconv.Itoa(g.Luck.Best[p]) + "   Par " + strconv.Itoa(g.Luck.Par[p]) +
+				"\nDealt " + signed(g.Luck.Luck[p]) + "   Played " + signed(g.Luck.Skill[p])
+			screen.DrawTextCenteredAt(text, 22, x+ui.TILE_X_OFFSET*1.5, 650, color.White)
+		}
+	} else if g.LuckErr != nil {
+		screen.DrawTextCenteredAt("Luck report unavailable", 22, g.Layout.StartX[0]+ui.TILE_X_OFFSET*1.5, 650, HintColor)
+	}
+}
+
+func signed(n int) string {
+	if n > 0 {
+		return "+" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// OnGameOver keeps the finished game for replays and starts the luck report.
+func (g *GameScene) OnGameOver() {
+	if g.Tutorial != nil {
+		return
+	}
+	RememberGame(g.Game.Record())
+	if g.Puzzles == nil && g.Game.Start == "" {
+		g.SaveMatchResults()
+	}
+	if g.Game.Solo {
+		g.StartSoloRating()
+		return
+	}
+	g.StartLuckReport()
+	if g.Daily != "" {
+		g.SaveDailyResult()
+	}
+}
+
+// SaveDailyResult adds the finished challenge to the daily history. Only the
+// first game of each day counts.
+func (g *GameScene) SaveDailyResult() {
+	h, err := core.LoadDailyHistory()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	g.DailyKept = h.Add(core.NewDailyResult(g.Daily, g.Game))
+	if g.DailyKept {
+		if err := h.Save(); err != nil {
+			log.Println(err)
+		}
+	}
+	g.DailyStreak = h.Streak(g.Daily)
+	dailyHistoryChanged = true
+}
+
+// StartLuckReport measures luck and skill for the finished game in the
+// background, since the hindsight search takes a noticeable moment.
+func (g *GameScene) StartLuckReport() {
+	record, model := g.Game.Record(), g.HintModel
+	go func() {
+		r, err := core.MeasureLuck(record, model)
+		if err != nil {
+			log.Println("luck report:", err)
+		}
+		g.luckChan <- luckResult{r, err}
+	}()
+}
+
+type luckResult struct {
+	report *core.LuckReport
+	err    error
+}
+
+// StartSoloRating works out the best pyramid the player could have built,
+// which takes a moment.
+func (g *GameScene) StartSoloRating() {
+	record := g.Game.Record()
+	go func() {
+		r, err := core.RateSoloGame(record)
+		if err == nil {
+			g.soloChan <- r
+		} else {
+			log.Println(err)
+		}
+	}()
+}
+
+// SaveSoloRating adds the rated game to the personal records.
+func (g *GameScene) SaveSoloRating(r *core.SoloRating) {
+	g.SoloRating = r
+	if g.SoloStats == nil {
+		g.SoloStats = &core.SoloStats{}
+	}
+	g.SoloBest = g.SoloStats.Add(r)
+	if err := g.SoloStats.Save(); err != nil {
+		log.Println(err)
+	}
+}
+
 func XYinHexCell(x, y float64, Hx, Hy, Hw, Hh, Hth float64) bool {
 	if !util.XYinRect(x, y, Hx, Hy, Hw, Hh) {
 		return false
@@ -305,19 +758,30 @@ func (g *GameScene) PyramidXYForTurn(i int) (*core.Pyramid, float64, float64) {
 		return nil, 0, 0
 	}
 
-	x, y := P0XLocs[i], P0YLocs[i]
+	x, y := g.Layout.XLocs[0][i], g.Layout.YLocs[0][i]
 	pyramid := g.Game.Pyramid1
-	if g.Game.Turn%2 == 1 {
-		x, y = P1XLocs[i], P1YLocs[i]
+	if g.Game.CurrentPlayer() == 1 {
+		x, y = g.Layout.XLocs[1][i], g.Layout.YLocs[1][i]
 		pyramid = g.Game.Pyramid2
 	}
 	return pyramid, x, y
 }
 
 func (g *GameScene) Update() {
+	if g.Paused {
+		g.UpdatePause()
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) && !g.ShowRules {
+		g.Paused = true
+		g.PauseMessage = ""
+		return
+	}
+
 	if g.UIState == WAITING_FOR_PLAYER_MOVE && g.Agents[g.Game.CurrentPlayer()] != nil {
 		g.UIState = WAITING_FOR_OPP_MOVE
-		g.moveChan <- g.Agents[g.Game.CurrentPlayer()].GenerateMove()
+		g.HelpText = "The computer is thinking..."
+		g.startAgentMove(g.Agents[g.Game.CurrentPlayer()])
 		return
 	}
 
@@ -328,35 +792,46 @@ func (g *GameScene) Update() {
 		return
 	}
 
+	select {
+	case l := <-g.luckChan:
+		g.Luck, g.LuckErr = l.report, l.err
+	case r := <-g.soloChan:
+		g.SaveSoloRating(r)
+	default:
+	}
+
 	select {
 	case m := <-g.moveChan:
+		m, c, err := g.applyAgentMove(m)
+		if err != nil {
+			log.Println(err)
+			g.StopAgents()
+			g.UIState = GAME_OVER
+			g.HelpText = "The computer couldn't move. Click anywhere to return to main menu."
+			break
+		}
 		if m.EventType == core.DRAW_CARDS {
 			//player := g.AudioContext.NewPlayerFromBytes(g.ActionSound)
 			// NOTE: not sure why these always seem delayed
 			//player.Play()
 			//fmt.Println("received event draw card")
-			c := g.Game.DrawCard()
-			g.Agents[g.Game.Turn%2].RevealCard(c)
-			g.Agents[g.Game.Turn%2].SetVisibleCard(c)
+			g.Agents[g.Game.CurrentPlayer()].SetVisibleCard(c)
 			g.SecondSprite = g.DiscardSprite
-			g.DiscardSprite = ui.NewCardSprite(c, DECK_BUTTON_X, DECK_BUTTON_Y)
-			g.AnimationQueue = append(g.AnimationQueue, ui.NewBlockingAnim(30), ui.NewLinearPathAnimator(g.DiscardSprite, 35,
-				ui.Location{X: DECK_BUTTON_X, Y: DECK_BUTTON_Y},
-				ui.Location{X: DISCARD_X, Y: DISCARD_Y}, ui.EaseOutCubic, func() {
-					go func() {
-						g.moveChan <- g.Agents[g.Game.CurrentPlayer()].GenerateMove()
-					}()
+			g.DiscardSprite = ui.NewCardSprite(c, g.Layout.DeckX, g.Layout.DeckY)
+			g.AnimationQueue = append(g.AnimationQueue, ui.NewBlockingAnim(Frames(30)), ui.NewLinearPathAnimator(g.DiscardSprite, Frames(35),
+				ui.Location{X: g.Layout.DeckX, Y: g.Layout.DeckY},
+				ui.Location{X: g.Layout.DiscardX, Y: g.Layout.DiscardY}, ui.EaseOutCubic, func() {
+					g.startAgentMove(g.Agents[g.Game.CurrentPlayer()])
 				}))
 
 		} else if m.EventType == core.PLAY_CARD {
 			//fmt.Println("received event play card")
 			//player := g.AudioContext.NewPlayerFromBytes(g.ActionSound)
 			//player.Play()
-			g.Game.PlayCard(m.Target)
 			complete := func() {
 				// this code is almost repeated, but its fine for now
 				var sprites [10]*ui.CardSprite
-				if g.Game.Turn%2 == 0 {
+				if g.LastMover() == 1 {
 					sprites = g.P1Spheres
 				} else {
 					sprites = g.P0Spheres
@@ -391,21 +866,21 @@ func (g *GameScene) Update() {
 
 				nextCard := g.Game.TopDiscard()
 				if nextCard != nil {
-					g.DiscardSprite = ui.NewCardSprite(nextCard, DISCARD_X, DISCARD_Y)
+					g.DiscardSprite = ui.NewCardSprite(nextCard, g.Layout.DiscardX, g.Layout.DiscardY)
 				}
 				if len(g.Game.Discards) > 1 {
-					g.SecondSprite = ui.NewCardSprite(g.Game.Discards[len(g.Game.Discards)-2], DISCARD_X, DISCARD_Y)
-					g.SecondSprite.X = DISCARD_X
-					g.SecondSprite.Y = DISCARD_Y
+					g.SecondSprite = ui.NewCardSprite(g.Game.Discards[len(g.Game.Discards)-2], g.Layout.DiscardX, g.Layout.DiscardY)
+					g.SecondSprite.X = g.Layout.DiscardX
+					g.SecondSprite.Y = g.Layout.DiscardY
 				} else {
 					g.SecondSprite = nil
 				}
 
 				g.P0Score = g.Game.Pyramid1.Score()
 				g.P1Score = g.Game.Pyramid2.Score()
-				g.CurrentTurn = 1 - g.CurrentTurn
+				g.CurrentTurn = g.Game.CurrentPlayer()
 				if g.Game.State == core.IN_PROGRESS {
-					if g.Agents[g.Game.Turn%2] == nil {
+					if g.Agents[g.Game.CurrentPlayer()] == nil {
 						g.UIState = WAITING_FOR_PLAYER_MOVE
 						if len(g.Game.Discards) > 0 {
 							g.HelpText = "Drag the open card to your pyramid or click the deck to reveal a new card."
@@ -413,26 +888,27 @@ func (g *GameScene) Update() {
 							g.HelpText = "Click the deck to reveal a card."
 						}
 					} else {
-						g.Agents[g.Game.Turn%2].SetVisibleCard(g.Game.TopDiscard())
-						go func() { g.moveChan <- g.Agents[g.Game.Turn%2].GenerateMove() }()
+						g.Agents[g.Game.CurrentPlayer()].SetVisibleCard(g.Game.TopDiscard())
+						g.startAgentMove(g.Agents[g.Game.CurrentPlayer()])
 					}
 				} else {
 					g.UIState = GAME_OVER
 					g.HelpText = "Game Over."
+					g.OnGameOver()
 				}
 			}
-			if g.Game.Turn%2 == 0 {
+			if g.LastMover() == 1 {
 				g.P1Spheres[m.Target] = g.DiscardSprite // ui.NewCardSprite(card, P2XLocs[m.Target], P2YLocs[m.Target])
 				g.DiscardSprite = nil
-				g.AnimationQueue = append(g.AnimationQueue, ui.NewBlockingAnim(30), ui.NewLinearPathAnimator(g.P1Spheres[m.Target], 50,
-					ui.Location{X: DISCARD_X, Y: DISCARD_Y},
-					ui.Location{X: P1XLocs[m.Target], Y: P1YLocs[m.Target] - ui.TILE_HEIGHT}, ui.EaseOutCubic, complete))
+				g.AnimationQueue = append(g.AnimationQueue, ui.NewBlockingAnim(Frames(30)), ui.NewLinearPathAnimator(g.P1Spheres[m.Target], Frames(50),
+					ui.Location{X: g.Layout.DiscardX, Y: g.Layout.DiscardY},
+					ui.Location{X: g.Layout.XLocs[1][m.Target], Y: g.Layout.YLocs[1][m.Target] - ui.TILE_HEIGHT}, ui.EaseOutCubic, complete))
 			} else {
 				g.P0Spheres[m.Target] = g.DiscardSprite // ui.NewCardSprite(card, P2XLocs[m.Target], P2YLocs[m.Target])
 				g.DiscardSprite = nil
-				g.AnimationQueue = append(g.AnimationQueue, ui.NewBlockingAnim(30), ui.NewLinearPathAnimator(g.P0Spheres[m.Target], 50,
-					ui.Location{X: DISCARD_X, Y: DISCARD_Y},
-					ui.Location{X: P0XLocs[m.Target], Y: P0YLocs[m.Target] - ui.TILE_HEIGHT}, ui.EaseOutCubic, complete))
+				g.AnimationQueue = append(g.AnimationQueue, ui.NewBlockingAnim(Frames(30)), ui.NewLinearPathAnimator(g.P0Spheres[m.Target], Frames(50),
+					ui.Location{X: g.Layout.DiscardX, Y: g.Layout.DiscardY},
+					ui.Location{X: g.Layout.XLocs[0][m.Target], Y: g.Layout.YLocs[0][m.Target] - ui.TILE_HEIGHT}, ui.EaseOutCubic, complete))
 			}
 		}
 	default:
@@ -456,14 +932,51 @@ func (g *GameScene) Update() {
 	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && util.XYinRect(cx, cy, RULES_X-10, RULES_Y-10, 120, 35) {
 		g.ShowRules = true
 	}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && util.XYinRect(cx, cy, PAUSE_X-10, PAUSE_Y-10, 120, 35) {
+		g.Paused = true
+		g.PauseMessage = ""
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) ||
+		(inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && util.XYinRect(cx, cy, EDGES_X-10, EDGES_Y-10, 120, 35)) {
+		g.ShowEdges = !g.ShowEdges
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) ||
+		(inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && util.XYinRect(cx, cy, EDGES_X-10, TRACKER_TOGGLE_Y-10, 120, 35)) {
+		g.ShowTracker = !g.ShowTracker
+	} else if g.ShowTracker {
+		g.UpdateTracker(cx, cy)
+	}
+	g.UpdateHistory(cx, cy)
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		g.ShowStack = !g.ShowStack
+	}
 
 	if g.UIState == GAME_OVER {
 		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-			if util.XYinRect(cx, cy, 640-120, 550-20, 240, 40) {
+			x, y := g.Layout.ButtonX, g.Layout.ButtonY
+			if util.XYinRect(cx, cy, x-120, y-20, 240, 40) {
 				g.SceneManager.SwitchToScene("menu")
+			} else if util.XYinRect(cx, cy, x-120, y+50-20, 240, 40) {
+				rs := NewReviewScene(g.Game.Record(), g.HintModel)
+				rs.PlayerNames = g.seatNames()
+				g.SceneManager.AddScene("review", rs)
+				g.SceneManager.SwitchToScene("review")
+			} else if util.XYinRect(cx, cy, x-120, y+100-20, 240, 40) {
+				rs := NewReplayScene(g.Game.Record())
+				rs.PlayerNames = g.seatNames()
+				g.SceneManager.AddScene("replay", rs)
+				g.SceneManager.SwitchToScene("replay")
 			}
 		}
+	} else if g.UIState == PUZZLE_DONE {
+		g.UpdatePuzzleResult(cx, cy)
 	} else if g.UIState == WAITING_FOR_PLAYER_MOVE {
+		if g.HintsAllowed() && (inpututil.IsKeyJustPressed(ebiten.KeyH) ||
+			(inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && util.XYinRect(cx, cy, HINT_X-10, HINT_Y-10, 120, 35))) {
+			g.RequestHint()
+		}
+
 		g.PrevPend = g.PendIndex
 
 		InHex := false
@@ -473,7 +986,7 @@ func (g *GameScene) Update() {
 				if XYinHexCell(cx, cy, x, y, ui.TILE_SIZE_X, ui.TILE_SIZE_Y-ui.TILE_HEIGHT, ui.TILE_TIP_HEIGHT) && pyramid.CanPlace(i) {
 					g.PendIndex = i
 					if g.PendIndex != g.PrevPend {
-						if g.Game.Turn%2 == 0 {
+						if g.Game.CurrentPlayer() == 0 {
 							g.P0Score = pyramid.TentativeScoreWithCard(g.DragSprite.Card, g.PendIndex)
 						} else {
 							g.P1Score = pyramid.TentativeScoreWithCard(g.DragSprite.Card, g.PendIndex)
@@ -499,22 +1012,32 @@ func (g *GameScene) Update() {
 				g.DragSprite.ShadowType = 1
 				g.DragSprite.X = cx - ui.TILE_SIZE_X/2
 				g.DragSprite.Y = cy - (ui.TILE_SIZE_Y-ui.TILE_HEIGHT-6)/2
-			} else if util.XYinRect(cx, cy, DECK_BUTTON_X, DECK_BUTTON_Y, DECK_BUTTON_W, DECK_BUTTON_H) {
+			} else if util.XYinRect(cx, cy, g.Layout.DeckX, g.Layout.DeckY, DECK_BUTTON_W, DECK_BUTTON_H) {
 				if g.Game.DrawsLeft == 0 {
 					g.HelpText = "You have 0 draws remaining this turn. Drag the open card to your pyramid."
-				} else {
-					player := g.AudioContext.NewPlayerFromBytes(g.SlideSound)
-					player.Play()
-					c := g.Game.DrawCard()
-					if g.Agents[1-g.CurrentTurn] != nil {
-						g.Agents[1-g.CurrentTurn].RevealCard(c)
+				} else if g.Allowed(core.AgentEvent{EventType: core.DRAW_CARDS}) {
+					PlaySound(g.AudioContext, g.SlideSound)
+					g.Hint = nil
+					c, err := core.ApplyAndNotify(g.Game, g.Agents, core.AgentEvent{EventType: core.DRAW_CARDS})
+					if err != nil {
+						log.Println(err)
+						g.HelpText = "There are no cards left to reveal. Drag the open card to your pyramid."
+						return
+					}
+					if g.Tutorial != nil {
+						g.AdvanceTutorial(core.AgentEvent{EventType: core.DRAW_CARDS})
 					}
 					g.SecondSprite = g.DiscardSprite
-					g.DiscardSprite = ui.NewCardSprite(c, DECK_BUTTON_X, DECK_BUTTON_Y)
+					g.DiscardSprite = ui.NewCardSprite(c, g.Layout.DeckX, g.Layout.DeckY)
 					g.UIState = WAITING_FOR_PLAYER_ANIMIMATION
-					g.AnimationQueue = append(g.AnimationQueue, ui.NewLinearPathAnimator(g.DiscardSprite, 25,
-						ui.Location{X: DECK_BUTTON_X, Y: DECK_BUTTON_Y},
-						ui.Location{X: DISCARD_X, Y: DISCARD_Y}, ui.EaseOutCubic, func() { g.UIState = WAITING_FOR_PLAYER_MOVE }))
+					g.AnimationQueue = append(g.AnimationQueue, ui.NewLinearPathAnimator(g.DiscardSprite, Frames(25),
+						ui.Location{X: g.Layout.DeckX, Y: g.Layout.DeckY},
+						ui.Location{X: g.Layout.DiscardX, Y: g.Layout.DiscardY}, ui.EaseOutCubic, func() {
+							g.UIState = WAITING_FOR_PLAYER_MOVE
+							if g.Puzzles != nil {
+								g.FinishPuzzle(core.AgentEvent{EventType: core.DRAW_CARDS})
+							}
+						}))
 					if g.Game.DrawsLeft == 0 {
 						g.HelpText = "Drag the open card to your pyramid."
 					} else {
@@ -527,18 +1050,23 @@ func (g *GameScene) Update() {
 			if g.Stroke != nil {
 				g.Stroke.Release()
 				pyramid, x, y := g.PyramidXYForTurn(g.PendIndex)
-				if g.PendIndex != -1 && pyramid.CanPlace(g.PendIndex) {
-					player := g.AudioContext.NewPlayerFromBytes(g.ActionSound)
-					player.Play()
-					g.Game.PlayCard(g.PendIndex)
+				if g.PendIndex != -1 && pyramid.CanPlace(g.PendIndex) && g.Allowed(core.AgentEvent{EventType: core.PLAY_CARD, Target: g.PendIndex}) {
+					PlaySound(g.AudioContext, g.ActionSound)
+					g.Hint = nil
+					if _, err := core.ApplyAndNotify(g.Game, g.Agents, core.AgentEvent{EventType: core.PLAY_CARD, Target: g.PendIndex}); err != nil {
+						log.Println(err)
+						g.returnDragSprite()
+						g.SelectedCard, g.DragSprite, g.PendIndex = nil, nil, -1
+						return
+					}
 					if len(g.Game.Discards) > 0 {
-						g.DiscardSprite = ui.NewCardSprite(g.Game.TopDiscard(), DISCARD_X, DISCARD_Y)
-						g.DiscardSprite.X = DISCARD_X
-						g.DiscardSprite.Y = DISCARD_Y
+						g.DiscardSprite = ui.NewCardSprite(g.Game.TopDiscard(), g.Layout.DiscardX, g.Layout.DiscardY)
+						g.DiscardSprite.X = g.Layout.DiscardX
+						g.DiscardSprite.Y = g.Layout.DiscardY
 						if len(g.Game.Discards) > 1 {
-							g.SecondSprite = ui.NewCardSprite(g.Game.Discards[len(g.Game.Discards)-2], DISCARD_X, DISCARD_Y)
-							g.SecondSprite.X = DISCARD_X
-							g.SecondSprite.Y = DISCARD_Y
+							g.SecondSprite = ui.NewCardSprite(g.Game.Discards[len(g.Game.Discards)-2], g.Layout.DiscardX, g.Layout.DiscardY)
+							g.SecondSprite.X = g.Layout.DiscardX
+							g.SecondSprite.Y = g.Layout.DiscardY
 						} else {
 							g.SecondSprite = nil
 						}
@@ -554,9 +1082,9 @@ func (g *GameScene) Update() {
 					} else {
 						g.DragSprite.ShadowType = 2
 					}
-					// this conditional is flipped because we increment the turn counter during play card
+					// the card goes to the player who just moved, not the one to move now
 					var sprites [10]*ui.CardSprite
-					if g.Game.Turn%2 == 0 {
+					if g.LastMover() == 1 {
 						g.P1Spheres[g.PendIndex] = g.DragSprite
 						sprites = g.P1Spheres
 					} else {
@@ -593,26 +1121,26 @@ func (g *GameScene) Update() {
 
 					g.P0Score = g.Game.Pyramid1.Score()
 					g.P1Score = g.Game.Pyramid2.Score()
-					g.CurrentTurn = 1 - g.CurrentTurn
-					if g.Game.State == core.IN_PROGRESS {
-						if agent := g.Agents[g.Game.Turn%2]; agent != nil {
+					g.CurrentTurn = g.Game.CurrentPlayer()
+					if g.Tutorial != nil {
+						g.AdvanceTutorial(core.AgentEvent{EventType: core.PLAY_CARD, Target: g.PendIndex})
+					}
+					if g.Puzzles != nil {
+						g.FinishPuzzle(core.AgentEvent{EventType: core.PLAY_CARD, Target: g.PendIndex})
+					} else if g.Game.State == core.IN_PROGRESS {
+						if agent := g.Agents[g.Game.CurrentPlayer()]; agent != nil {
 							g.UIState = WAITING_FOR_OPP_MOVE
 							g.HelpText = "The computer is thinking..."
 							agent.SetVisibleCard(g.Game.TopDiscard())
-							go func() { g.moveChan <- agent.GenerateMove() }()
+							g.startAgentMove(agent)
 						}
 					} else {
 						g.UIState = GAME_OVER
 						g.HelpText = "Game Over. Click anywhere to return to main menu."
+						g.OnGameOver()
 					}
 				} else {
-					g.DragSprite.ShadowType = 0
-					g.UIState = WAITING_FOR_PLAYER_ANIMIMATION
-					g.AnimationQueue = append(g.AnimationQueue, ui.NewLinearPathAnimator(g.DragSprite, 15,
-						ui.Location{X: g.DragSprite.X, Y: g.DragSprite.Y},
-						ui.Location{X: DISCARD_X, Y: DISCARD_Y}, ui.EaseOutCubic, func() {
-							g.UIState = WAITING_FOR_PLAYER_MOVE
-						}))
+					g.returnDragSprite()
 				}
 				g.SelectedCard = nil
 				g.DragSprite = nil
@@ -0,0 +1,143 @@
+package scene
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+const HISTORY_X = 860
+const HISTORY_Y = 15
+const HISTORY_W = 270
+const HISTORY_LINES = 5
+const HISTORY_LINE_H = 22
+
+// HISTORY_NAME_LENGTH is as much of a player's name as fits beside the moves.
+const HISTORY_NAME_LENGTH = 10
+
+// shortName cuts name down to n characters, marking that it was cut.
+func shortName(name string, n int) string {
+	r := []rune(name)
+	if len(r) <= n {
+		return name
+	}
+	return string(r[:n-1]) + "."
+}
+
+// HistoryTurn is one player's turn: the cards they drew and the card they
+// placed, if they have placed it yet.
+type HistoryTurn struct {
+	Number int
+	Player int
+	Moves  []core.Move
+	Target int // the slot the turn filled, -1 until it ends
+}
+
+// HistoryTurns groups the moves of g into turns, numbered from the turn the
+// game was set up at.
+func HistoryTurns(g *core.Game) []HistoryTurn {
+	number := g.Turn + 1
+	for _, m := range g.History {
+		if m.EventType == core.PLAY_CARD {
+			number -= 1
+		}
+	}
+	turns := []HistoryTurn{}
+	for _, m := range g.History {
+		if l := len(turns); l == 0 || turns[l-1].Target != -1 {
+			turns = append(turns, HistoryTurn{Number: number, Player: m.Player, Target: -1})
+			number += 1
+		}
+		t := &turns[len(turns)-1]
+		t.Moves = append(t.Moves, m)
+		if m.EventType == core.PLAY_CARD {
+			t.Target = m.Target
+		}
+	}
+	return turns
+}
+
+// Text writes the turn in move notation after the name of the player, which is
+// left out if empty.
+func (t HistoryTurn) Text(player string) string {
+	parts := []string{strconv.Itoa(t.Number) + "."}
+	if player != "" {
+		parts = append(parts, player)
+	}
+	for _, m := range t.Moves {
+		parts = append(parts, m.Notation())
+	}
+	return strings.Join(parts, " ")
+}
+
+// historyRange is the part of turns the panel shows, scrolled up from the
+// latest turn by g.HistoryScroll lines.
+func (g *GameScene) historyRange(turns []HistoryTurn) (int, int) {
+	end := len(turns) - g.HistoryScroll
+	return max(0, end-HISTORY_LINES), end
+}
+
+// hoveredTurn is the turn under the cursor, or nil.
+func (g *GameScene) hoveredTurn(turns []HistoryTurn, cx, cy float64) *HistoryTurn {
+	start, end := g.historyRange(turns)
+	for i := start; i < end; i++ {
+		y := float64(HISTORY_Y + (i-start+1)*HISTORY_LINE_H)
+		if util.XYinRect(cx, cy, HISTORY_X, y, HISTORY_W, HISTORY_LINE_H) {
+			return &turns[i]
+		}
+	}
+	return nil
+}
+
+// UpdateHistory scrolls the panel with the mouse wheel.
+func (g *GameScene) UpdateHistory(cx, cy float64) {
+	if !util.XYinRect(cx, cy, HISTORY_X, HISTORY_Y, HISTORY_W, (HISTORY_LINES+1)*HISTORY_LINE_H+6) {
+		return
+	}
+	_, dy := ebiten.Wheel()
+	if dy > 0 {
+		g.HistoryScroll += 1
+	} else if dy < 0 {
+		g.HistoryScroll -= 1
+	}
+	turns := len(HistoryTurns(g.Game))
+	g.HistoryScroll = max(0, min(g.HistoryScroll, turns-HISTORY_LINES))
+}
+
+// DrawHistory lists the latest turns and outlines the slot filled by the turn
+// under the cursor.
+func (g *GameScene) DrawHistory(screen *ui.ScaledScreen) {
+	turns := HistoryTurns(g.Game)
+	screen.DrawUnfilledRect(HISTORY_X, HISTORY_Y, HISTORY_W, (HISTORY_LINES+1)*HISTORY_LINE_H+6, 1, color.White)
+	header := "Moves"
+	if g.HistoryScroll > 0 {
+		header += " (scrolled)"
+	}
+	screen.DrawText(header, 18, HISTORY_X+8, HISTORY_Y+3, color.White)
+
+	cx, cy := ui.AdjustedCursorPosition()
+	hovered := g.hoveredTurn(turns, cx, cy)
+	start, end := g.historyRange(turns)
+	for i := start; i < end; i++ {
+		c := color.Color(color.White)
+		if hovered == &turns[i] {
+			c = HintColor
+		}
+		player := ""
+		if !g.Game.Solo {
+			player = shortName(g.SeatName(turns[i].Player), HISTORY_NAME_LENGTH)
+		}
+		screen.DrawText(turns[i].Text(player), 18, HISTORY_X+8, HISTORY_Y+3+(i-start+1)*HISTORY_LINE_H, c)
+	}
+
+	if hovered != nil && hovered.Target != -1 {
+		opt := &ebiten.DrawImageOptions{}
+		opt.GeoM.Translate(g.Layout.XLocs[hovered.Player][hovered.Target], g.Layout.YLocs[hovered.Player][hovered.Target])
+		screen.DrawImage(g.HoverTile, opt)
+	}
+}
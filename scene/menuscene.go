@@ -1,12 +1,19 @@
 package scene
 
 import (
+	"errors"
 	"image/color"
+	"log"
 	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/core"
 	"github.com/prizelobby/pyramid-rummy/res"
 	"github.com/prizelobby/pyramid-rummy/ui"
 	"github.com/prizelobby/pyramid-rummy/util"
@@ -18,36 +25,264 @@ const CENTER = 640
 const TITLE_Y_CENTER = 180
 const CHOICE_HEADER_Y = 280
 const PLAYING_Y_CENTER = 450
-const RULES_Y_CENTER = 550
+const SOLO_Y_CENTER = 500
+const DAILY_Y_CENTER = 545
+const RULES_Y_CENTER = 595
+const REPLAY_Y_CENTER = 645
+const OPEN_CODE_Y_CENTER = 690
 
 type MenuScene struct {
 	BaseScene
 	AudioContext *audio.Context
 	Sound        []byte
 
-	P0Choice int
-	P1Choice int
+	Profiles   *core.Profiles
+	SeatAgents [2]string // the agent for each seat, empty for a human
+	SeatNames  [2]string // the player in each human seat
+
+	EditingSeat int // the seat whose name is being typed, -1 if none
+	NameText    string
+	NameError   string
 
 	Rules        *ui.RulesComponent
 	ShowingRules bool
+
+	DailyStreak int
+
+	SavedGame *core.SavedGame
+
+	EnteringCode bool
+	CodeText     string
+	CodeError    string
+	pasteChan    chan string
 }
 
 func NewMenuScene(audioContext *audio.Context) *MenuScene {
 	b := res.DecodeWavToBytes(audioContext, "dice_03.wav")
 
+	profiles, err := core.LoadProfiles()
+	if err != nil {
+		log.Println(err)
+	}
 	return &MenuScene{
 		AudioContext: audioContext,
 		Sound:        b,
-		P1Choice:     1,
+		Profiles:     profiles,
+		SeatAgents:   [2]string{"", CurrentSettings.Opponent},
+		SeatNames:    [2]string{profiles.SeatName(0), profiles.SeatName(1)},
+		EditingSeat:  -1,
+		pasteChan:    make(chan string, 1),
 
 		Rules: ui.NewRulesComponent(),
 	}
 }
 
 func (m *MenuScene) OnSwitch() {
+	m.UpdateProfiles()
+}
+
+// dailyHistoryChanged tells the menu to read the daily history again.
+var dailyHistoryChanged = true
+
+func (m *MenuScene) UpdateDailyStreak() {
+	if h, err := core.LoadDailyHistory(); err == nil {
+		m.DailyStreak = h.Streak(core.DailyDate(time.Now()))
+	}
+	dailyHistoryChanged = false
+}
+
+func (m *MenuScene) UpdateSavedGame() {
+	s, err := core.LoadSavedGame()
+	if err != nil {
+		log.Println(err)
+	}
+	m.SavedGame = s
+	savedGameChanged = false
+}
+
+// ContinueSavedGame picks up the saved game. It is no longer kept once
+// picked up, leaving the game saves it again.
+func (m *MenuScene) ContinueSavedGame() {
+	gs, err := NewSavedGameScene(m.SavedGame, m.AudioContext)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if err := core.ClearSavedGame(); err != nil {
+		log.Println(err)
+	}
+	savedGameChanged = true
+	m.SceneManager.AddScene("game", gs)
+	m.SceneManager.SwitchToScene("game")
+	PlaySound(m.AudioContext, m.Sound)
+}
+
+// playX is where Play is drawn, moved aside for Continue when there is a
+// saved game.
+func (m *MenuScene) playX() float64 {
+	if m.SavedGame != nil {
+		return CENTER - 110
+	}
+	return CENTER
+}
+
+// StartGame plays a new game with the seats as they are set up.
+func (m *MenuScene) StartGame() {
+	gs, err := NewSceneForGame(core.NewGame(), m.SeatAgents, m.SeatNames, m.AudioContext)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	m.SceneManager.AddScene("game", gs)
+	m.SceneManager.SwitchToScene("game")
+	PlaySound(m.AudioContext, m.Sound)
+}
+
+// StartDaily plays today's challenge as the player in the first seat, or
+// picks it up if it was saved part way through so it isn't dealt again.
+func (m *MenuScene) StartDaily() {
+	today := core.DailyDate(time.Now())
+	if m.SavedGame != nil && m.SavedGame.Daily == today {
+		m.ContinueSavedGame()
+		return
+	}
+	gs, err := NewDailyGameScene(today, m.AudioContext)
+	if err != nil {
+		return
+	}
+	gs.PlayerNames[0] = m.SeatNames[0]
+	m.SceneManager.AddScene("game", gs)
+	m.SceneManager.SwitchToScene("game")
+	PlaySound(m.AudioContext, m.Sound)
+}
+
+func (m *MenuScene) StartSolo() {
+	gs := NewSoloGameScene(m.AudioContext)
+	gs.PlayerNames[0] = m.SeatNames[0]
+	m.SceneManager.AddScene("game", gs)
+	m.SceneManager.SwitchToScene("game")
+	PlaySound(m.AudioContext, m.Sound)
+}
+
+// StartPuzzles opens the first puzzle that hasn't been solved yet.
+func (m *MenuScene) StartPuzzles() {
+	puzzles, err := LoadPuzzleSet()
+	if err != nil || len(puzzles) == 0 {
+		log.Println("no puzzles", err)
+		return
+	}
+	progress, err := core.LoadPuzzleProgress()
+	if err != nil {
+		log.Println(err)
+	}
+	gs, err := NewPuzzleGameScene(puzzles, progress.Next(puzzles), progress, m.AudioContext)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	m.SceneManager.AddScene("game", gs)
+	m.SceneManager.SwitchToScene("game")
+	PlaySound(m.AudioContext, m.Sound)
+}
+
+// OpenGameCode shows the game a share code was made from in the replay
+// viewer.
+func (m *MenuScene) OpenGameCode(code string) error {
+	record, err := core.ParseShareCode(code)
+	if err != nil {
+		return err
+	}
+	m.SceneManager.AddScene("replay", NewReplayScene(record))
+	m.SceneManager.SwitchToScene("replay")
+	return nil
+}
+
+// OpenArchive shows the games of an archive file, such as one written by
+// play -save, in the replay viewer, starting with the last.
+func (m *MenuScene) OpenArchive(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	games, err := core.ReadArchive(f)
+	if err != nil {
+		return err
+	}
+	if len(games) == 0 {
+		return errors.New(path + " has no games")
+	}
+	m.SceneManager.AddScene("replay", NewArchiveReplayScene(games))
+	m.SceneManager.SwitchToScene("replay")
+	return nil
+}
+
+// addCodeChars types the characters of s that can be in a game code. A pasted
+// link is cut down to the code it opens.
+func (m *MenuScene) addCodeChars(s string) {
+	if _, code, ok := strings.Cut(s, "game="); ok {
+		s = code
+	}
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '2' && r <= '7') {
+			m.CodeText += strings.ToUpper(string(r))
+			m.CodeError = ""
+		}
+	}
+}
+
+func (m *MenuScene) UpdateCodeEntry() {
+	select {
+	case text := <-m.pasteChan:
+		m.addCodeChars(text)
+	default:
+	}
+	shortcut := ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)
+	if shortcut && inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		paste := m.pasteChan
+		util.ReadClipboard(func(text string) {
+			select {
+			case paste <- text:
+			default:
+			}
+		})
+	} else if !shortcut {
+		m.addCodeChars(string(ebiten.AppendInputChars(nil)))
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(m.CodeText) > 0 {
+		m.CodeText = m.CodeText[:len(m.CodeText)-1]
+		m.CodeError = ""
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		if err := m.OpenGameCode(m.CodeText); err != nil {
+			m.CodeError = err.Error()
+		} else {
+			m.EnteringCode = false
+			m.CodeText = ""
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		m.EnteringCode = false
+		m.CodeText = ""
+		m.CodeError = ""
+	}
 }
 
 func (m *MenuScene) Update() {
+	if dailyHistoryChanged {
+		m.UpdateDailyStreak()
+	}
+	if savedGameChanged {
+		m.UpdateSavedGame()
+	}
+	if m.EnteringCode {
+		m.UpdateCodeEntry()
+		return
+	}
+	if m.EditingSeat != -1 {
+		m.UpdateNameEntry()
+		return
+	}
 	if m.ShowingRules {
 		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
 			m.ShowingRules = false
@@ -58,23 +293,39 @@ func (m *MenuScene) Update() {
 	cx, cy := ui.AdjustedCursorPosition()
 	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
 
-		if math.Abs(cx-CENTER) < 100 && math.Abs(cy-PLAYING_Y_CENTER) < 50 {
-			gs := NewGameScene(m.P0Choice, m.P1Choice, m.AudioContext)
-			m.SceneManager.AddScene("game", gs)
-			m.SceneManager.SwitchToScene("game")
-			player := m.AudioContext.NewPlayerFromBytes(m.Sound)
-			player.Play()
+		if m.SavedGame != nil && math.Abs(cx-(CENTER+110)) < 100 && math.Abs(cy-PLAYING_Y_CENTER) < 30 {
+			m.ContinueSavedGame()
+		} else if math.Abs(cx-m.playX()) < 100 && math.Abs(cy-PLAYING_Y_CENTER) < 30 {
+			m.StartGame()
 		}
-		if util.XYinRect(cx, cy, CENTER-100-48, CHOICE_HEADER_Y+40-20, 48*2, 20*2) {
-			m.P0Choice = 0
-		} else if util.XYinRect(cx, cy, CENTER-100-48, CHOICE_HEADER_Y+80-20, 48*2, 20*2) {
-			m.P0Choice = 1
-		} else if util.XYinRect(cx, cy, CENTER+100-48, CHOICE_HEADER_Y+40-20, 48*2, 20*2) {
-			m.P1Choice = 0
-		} else if util.XYinRect(cx, cy, CENTER+100-48, CHOICE_HEADER_Y+80-20, 48*2, 20*2) {
-			m.P1Choice = 1
-		} else if util.XYinRect(cx, cy, CENTER-48, RULES_Y_CENTER-20, 48*2, 20*2) {
+		if m.UpdateSeats(cx, cy) {
+			return
+		}
+		if util.XYinRect(cx, cy, CENTER-110-48, RULES_Y_CENTER-20, 48*2, 20*2) {
 			m.ShowingRules = true
+		} else if util.XYinRect(cx, cy, CENTER+110-80, RULES_Y_CENTER-20, 80*2, 20*2) {
+			m.SceneManager.AddScene("game", NewTutorialGameScene(m.AudioContext))
+			m.SceneManager.SwitchToScene("game")
+		} else if len(RecentGames) > 0 && util.XYinRect(cx, cy, CENTER-130, REPLAY_Y_CENTER-20, 260, 40) {
+			m.SceneManager.AddScene("replay", NewReplayScene(RecentGames[len(RecentGames)-1]))
+			m.SceneManager.SwitchToScene("replay")
+		} else if util.XYinRect(cx, cy, CENTER-110-70, SOLO_Y_CENTER-20, 140, 40) {
+			m.StartSolo()
+		} else if util.XYinRect(cx, cy, CENTER+110-70, SOLO_Y_CENTER-20, 140, 40) {
+			m.StartPuzzles()
+		} else if util.XYinRect(cx, cy, CENTER-130, DAILY_Y_CENTER-20, 260, 40) {
+			m.StartDaily()
+		} else if util.XYinRect(cx, cy, CENTER-140-120, OPEN_CODE_Y_CENTER-20, 240, 40) {
+			m.EnteringCode = true
+		} else if util.XYinRect(cx, cy, CENTER+140-70, OPEN_CODE_Y_CENTER-20, 140, 40) {
+			m.SceneManager.AddScene("sandbox", NewSandboxScene())
+			m.SceneManager.SwitchToScene("sandbox")
+		} else if util.XYinRect(cx, cy, RULES_X-10, RULES_Y-10, 140, 35) {
+			m.SceneManager.AddScene("settings", NewSettingsScene("menu"))
+			m.SceneManager.SwitchToScene("settings")
+		} else if util.XYinRect(cx, cy, RULES_X-10, RULES_Y+35-10, 140, 35) {
+			m.SceneManager.AddScene("stats", NewStatsScene())
+			m.SceneManager.SwitchToScene("stats")
 		}
 
 		/*
@@ -92,33 +343,52 @@ func (m *MenuScene) Draw(screen *ui.ScaledScreen) {
 		m.Rules.Draw(screen)
 		return
 	}
+	if m.EnteringCode {
+		m.DrawCodeEntry(screen)
+		return
+	}
+	if m.EditingSeat != -1 {
+		m.DrawNameEntry(screen)
+		return
+	}
 
 	screen.DrawTextCenteredAt("Rummy Pyramid", 56.0, CENTER, TITLE_Y_CENTER, color.White)
-	screen.DrawTextCenteredAt("Play", 48.0, CENTER, PLAYING_Y_CENTER, color.White)
-	screen.DrawTextCenteredAt("Rules", 48.0, CENTER, RULES_Y_CENTER, color.White)
-
-	screen.DrawTextCenteredAt("Player 1", 32.0, CENTER-100, CHOICE_HEADER_Y, color.White)
-	screen.DrawTextCenteredAt("Human", 24.0, CENTER-100, CHOICE_HEADER_Y+40, color.White)
-	screen.DrawTextCenteredAt("Computer", 24.0, CENTER-100, CHOICE_HEADER_Y+80, color.White)
-	screen.DrawTextCenteredAt("Player 2", 32.0, CENTER+100, CHOICE_HEADER_Y, color.White)
-	screen.DrawTextCenteredAt("Human", 24.0, CENTER+100, CHOICE_HEADER_Y+40, color.White)
-	screen.DrawTextCenteredAt("Computer", 24.0, CENTER+100, CHOICE_HEADER_Y+80, color.White)
-
-	if m.P0Choice == 0 {
-		screen.DrawCircle(CENTER-100-48, CHOICE_HEADER_Y+40, 4, color.White)
-		screen.DrawCircle(CENTER-100+48, CHOICE_HEADER_Y+40, 4, color.White)
-	} else {
-		screen.DrawCircle(CENTER-100-60, CHOICE_HEADER_Y+80, 4, color.White)
-		screen.DrawCircle(CENTER-100+60, CHOICE_HEADER_Y+80, 4, color.White)
+	screen.DrawText("Settings", 18, RULES_X, RULES_Y, color.White)
+	screen.DrawText("Statistics", 18, RULES_X, RULES_Y+35, color.White)
+	screen.DrawTextCenteredAt("Play", 48.0, m.playX(), PLAYING_Y_CENTER, color.White)
+	if m.SavedGame != nil {
+		screen.DrawTextCenteredAt("Continue", 48.0, CENTER+110, PLAYING_Y_CENTER, color.White)
 	}
-
-	if m.P1Choice == 0 {
-		screen.DrawCircle(CENTER+100-48, CHOICE_HEADER_Y+40, 4, color.White)
-		screen.DrawCircle(CENTER+100+48, CHOICE_HEADER_Y+40, 4, color.White)
-	} else {
-		screen.DrawCircle(CENTER+100-60, CHOICE_HEADER_Y+80, 4, color.White)
-		screen.DrawCircle(CENTER+100+60, CHOICE_HEADER_Y+80, 4, color.White)
+	screen.DrawTextCenteredAt("Rules", 48.0, CENTER-110, RULES_Y_CENTER, color.White)
+	screen.DrawTextCenteredAt("Tutorial", 48.0, CENTER+110, RULES_Y_CENTER, color.White)
+	screen.DrawTextCenteredAt("Open game code", 32.0, CENTER-140, OPEN_CODE_Y_CENTER, color.White)
+	screen.DrawTextCenteredAt("Sandbox", 32.0, CENTER+140, OPEN_CODE_Y_CENTER, color.White)
+	screen.DrawTextCenteredAt("Solo", 32.0, CENTER-110, SOLO_Y_CENTER, color.White)
+	screen.DrawTextCenteredAt("Puzzles", 32.0, CENTER+110, SOLO_Y_CENTER, color.White)
+	daily := "Daily challenge"
+	if m.DailyStreak > 0 {
+		daily += " (" + strconv.Itoa(m.DailyStreak) + " day streak)"
+	}
+	screen.DrawTextCenteredAt(daily, 32.0, CENTER, DAILY_Y_CENTER, color.White)
+	if len(RecentGames) > 0 {
+		screen.DrawTextCenteredAt("Replay last game", 32.0, CENTER, REPLAY_Y_CENTER, color.White)
 	}
 
+	m.DrawSeats(screen)
+
 	//scaledScreen.DrawTextCenteredAt("Credits", 32.0, CENTER, CREDITS_Y_CENTER, color.White)
 }
+
+func (m *MenuScene) DrawCodeEntry(screen *ui.ScaledScreen) {
+	screen.DrawTextCenteredAt("Open game code", 48.0, CENTER, TITLE_Y_CENTER, color.White)
+	screen.DrawTextCenteredAt("Type or paste a game code, then press Enter. Esc to go back.", 24.0, CENTER, CHOICE_HEADER_Y, color.White)
+	screen.DrawUnfilledRect(CENTER-500, 380-30, 1000, 60, 2, color.White)
+	text := m.CodeText
+	if len(text) > 48 {
+		text = "..." + text[len(text)-45:]
+	}
+	screen.DrawTextCenteredAt(text+"_", 28.0, CENTER, 380, color.White)
+	if m.CodeError != "" {
+		screen.DrawTextCenteredAt(m.CodeError, 24.0, CENTER, 460, BlunderColor)
+	}
+}
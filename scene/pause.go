@@ -0,0 +1,227 @@
+package scene
+
+import (
+	"image/color"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+const PAUSE_X = 1150
+const PAUSE_Y = 690
+const PAUSE_MENU_Y = 250
+const PAUSE_MENU_STEP = 50
+
+var PauseBackground = color.RGBA{0x22, 0x2e, 0x24, 0xe8}
+
+// savedGameChanged tells the menu to check for a saved game again.
+var savedGameChanged = true
+
+// NewSceneForGame shows game, which may already be under way, with an agent
+// made from each non-empty agent name. Human seats without a player name keep
+// the default one.
+func NewSceneForGame(game *core.Game, agentNames, playerNames [2]string, audioContext *audio.Context) (*GameScene, error) {
+	g := NewGameScene(audioContext)
+	for i, name := range playerNames {
+		if name != "" {
+			g.PlayerNames[i] = name
+		}
+	}
+	for i, name := range agentNames {
+		if name == "" {
+			continue
+		}
+		a, err := core.NewAgent(name, i, time.Now().UnixNano()+int64(i), game.Rules)
+		if err != nil {
+			return nil, err
+		}
+		g.Agents[i] = a
+	}
+	g.AgentNames = agentNames
+	if game.Solo {
+		g.Layout = SoloLayout
+		stats, err := core.LoadSoloStats()
+		if err != nil {
+			log.Println(err)
+		}
+		g.SoloStats = stats
+	}
+	g.SetGame(game)
+	core.SetPosition(game, g.Agents)
+	return g, nil
+}
+
+// NewSavedGameScene picks up a saved game where it was left.
+func NewSavedGameScene(s *core.SavedGame, audioContext *audio.Context) (*GameScene, error) {
+	game, err := s.Game()
+	if err != nil {
+		return nil, err
+	}
+	if s.Daily == "" {
+		return NewSceneForGame(game, s.Agents, s.Players, audioContext)
+	}
+	g, err := NewDailyGameScene(s.Daily, audioContext)
+	if err != nil {
+		return nil, err
+	}
+	if s.Players[0] != "" {
+		g.PlayerNames[0] = s.Players[0]
+	}
+	g.SetGame(game)
+	core.SetPosition(game, g.Agents)
+	return g, nil
+}
+
+type PauseItem struct {
+	Label  string
+	Action func()
+}
+
+// Saveable is false for puzzles and the tutorial, which are quick to start
+// again, and for finished games.
+func (g *GameScene) Saveable() bool {
+	return g.Puzzles == nil && g.Tutorial == nil && g.Game.Start == "" && g.Game.State == core.IN_PROGRESS
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func (g *GameScene) PauseItems() []PauseItem {
+	items := []PauseItem{{"Resume", func() { g.Paused = false }}}
+	// the daily deal is the same for everyone, so it can't be started again
+	// once its cards have been seen
+	if g.Daily == "" {
+		items = append(items, PauseItem{"Restart", func() { g.Restart(false) }})
+	}
+	if g.Puzzles == nil && g.Tutorial == nil && g.Daily == "" {
+		items = append(items, PauseItem{"Restart with a new deal", func() { g.Restart(true) }})
+	}
+	if g.Game.Start == "" {
+		items = append(items, PauseItem{"Copy game code", g.CopyGameCode})
+	}
+	if g.Saveable() {
+		items = append(items, PauseItem{"Save and quit", g.SaveAndQuit})
+	} else {
+		items = append(items, PauseItem{"Quit to menu", g.Quit})
+	}
+	items = append(items,
+		PauseItem{"Settings", g.OpenSettings},
+		PauseItem{"Edge overlay: " + onOff(g.ShowEdges), func() { g.ShowEdges = !g.ShowEdges }},
+		PauseItem{"Card tracker: " + onOff(g.ShowTracker), func() { g.ShowTracker = !g.ShowTracker }},
+	)
+	return items
+}
+
+// Restart starts the same kind of game again, with the same deal or a new
+// one.
+func (g *GameScene) Restart(newDeal bool) {
+	var gs *GameScene
+	var err error
+	if g.Tutorial != nil {
+		gs = NewTutorialGameScene(g.AudioContext)
+	} else if g.Puzzles != nil {
+		gs, err = NewPuzzleGameScene(g.Puzzles, g.PuzzleIndex, g.PuzzleProgress, g.AudioContext)
+	} else if g.Daily != "" {
+		gs, err = NewDailyGameScene(g.Daily, g.AudioContext)
+	} else {
+		r := g.Game.Record()
+		r.History = nil
+		if newDeal {
+			r.Seed = time.Now().UnixNano()
+		}
+		var game *core.Game
+		if game, err = r.NewGame(); err == nil {
+			gs, err = NewSceneForGame(game, g.AgentNames, g.PlayerNames, g.AudioContext)
+		}
+	}
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	gs.PlayerNames = g.PlayerNames
+	g.StopAgents()
+	g.SceneManager.AddScene("game", gs)
+	g.SceneManager.SwitchToScene("game")
+}
+
+func (g *GameScene) SaveAndQuit() {
+	s, err := core.NewSavedGame(g.Game, g.AgentNames, g.PlayerNames, g.Daily)
+	if err == nil {
+		err = s.Save()
+	}
+	if err != nil {
+		log.Println(err)
+		g.PauseMessage = "Couldn't save the game: " + err.Error()
+		return
+	}
+	savedGameChanged = true
+	g.Quit()
+}
+
+// CopyGameCode puts the share code of the game so far on the clipboard.
+func (g *GameScene) CopyGameCode() {
+	code, err := core.ShareCode(g.Game.Record())
+	if err == nil {
+		err = util.WriteClipboard(code)
+	}
+	if err != nil {
+		log.Println(err)
+		g.PauseMessage = "Couldn't copy the game code: " + err.Error()
+		return
+	}
+	g.PauseMessage = "Game code copied."
+}
+
+// OpenSettings leaves the game paused, so it is still paused on coming back.
+func (g *GameScene) OpenSettings() {
+	g.SceneManager.AddScene("settings", NewSettingsScene("game"))
+	g.SceneManager.SwitchToScene("settings")
+}
+
+func (g *GameScene) Quit() {
+	g.StopAgents()
+	g.Paused = false
+	g.SceneManager.SwitchToScene("menu")
+}
+
+func (g *GameScene) UpdatePause() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.Paused = false
+		return
+	}
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	cx, cy := ui.AdjustedCursorPosition()
+	for i, item := range g.PauseItems() {
+		if util.XYinRect(cx, cy, 640-160, PAUSE_MENU_Y+float64(i*PAUSE_MENU_STEP)-20, 320, 40) {
+			item.Action()
+			return
+		}
+	}
+}
+
+func (g *GameScene) DrawPause(screen *ui.ScaledScreen) {
+	items := g.PauseItems()
+	h := float64(len(items)*PAUSE_MENU_STEP + 130)
+	screen.DrawRect(640-200, PAUSE_MENU_Y-100, 400, h, PauseBackground)
+	screen.DrawTextCenteredAt("Paused", 48, 640, PAUSE_MENU_Y-60, color.White)
+	for i, item := range items {
+		y := PAUSE_MENU_Y + float64(i*PAUSE_MENU_STEP)
+		screen.DrawUnfilledRect(640-160, y-20, 320, 40, 2, color.White)
+		screen.DrawTextCenteredAt(item.Label, 28, 640, y, color.White)
+	}
+	if g.PauseMessage != "" {
+		screen.DrawTextCenteredAt(g.PauseMessage, 20, 640, PAUSE_MENU_Y-100+h+20, BlunderColor)
+	}
+}
@@ -0,0 +1,149 @@
+package scene
+
+import (
+	"image/color"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/res"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+const PUZZLE_FILE = "puzzles.json"
+
+var puzzleSet []*core.Puzzle
+
+// LoadPuzzleSet reads the puzzles shipped with the game the first time they
+// are needed.
+func LoadPuzzleSet() ([]*core.Puzzle, error) {
+	if puzzleSet != nil {
+		return puzzleSet, nil
+	}
+	data, err := res.ReadData(PUZZLE_FILE)
+	if err != nil {
+		return nil, err
+	}
+	puzzleSet, err = core.ParsePuzzles(data)
+	return puzzleSet, err
+}
+
+// NewPuzzleGameScene sets up puzzle index of puzzles. The player makes one
+// move for whoever is to move and is then told how it compares to the answer.
+func NewPuzzleGameScene(puzzles []*core.Puzzle, index int, progress *core.PuzzleProgress, audioContext *audio.Context) (*GameScene, error) {
+	g := NewGameScene(audioContext)
+	game, err := puzzles[index].Game(time.Now().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	g.Agents = [2]core.GameAgent{}
+	g.AgentNames = [2]string{}
+	g.Puzzles = puzzles
+	g.PuzzleIndex = index
+	g.PuzzleProgress = progress
+	g.SetGame(game)
+	g.HelpText = "Find the best move for Player " + strconv.Itoa(g.CurrentTurn+1) + ": place the open card or draw."
+	return g, nil
+}
+
+func (g *GameScene) Puzzle() *core.Puzzle {
+	if g.Puzzles == nil {
+		return nil
+	}
+	return g.Puzzles[g.PuzzleIndex]
+}
+
+// FinishPuzzle ends the puzzle once the player has made their move, and saves
+// it as solved if the move was the answer.
+func (g *GameScene) FinishPuzzle(e core.AgentEvent) {
+	g.UIState = PUZZLE_DONE
+	g.PuzzleChoice = e
+	p := g.Puzzle()
+	if e.String() != p.Answer {
+		g.HelpText = "Not quite. The best move was " + p.Answer + "."
+		return
+	}
+	g.HelpText = "Correct, " + p.Answer + " is the best move."
+	if g.PuzzleProgress != nil && !g.PuzzleProgress.Solved[p.ID] {
+		g.PuzzleProgress.Solved[p.ID] = true
+		if err := g.PuzzleProgress.Save(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (g *GameScene) DrawPuzzleStatus(screen *ui.ScaledScreen) {
+	p := g.Puzzle()
+	title := "Puzzle " + strconv.Itoa(g.PuzzleIndex+1) + " of " + strconv.Itoa(len(g.Puzzles))
+	screen.DrawTextCenteredAt(title, 48, 640, TURN_TEXT_Y, color.White)
+	status := strings.ToUpper(p.Difficulty[:1]) + p.Difficulty[1:]
+	if g.PuzzleProgress != nil {
+		solved := 0
+		for _, pz := range g.Puzzles {
+			if g.PuzzleProgress.Solved[pz.ID] {
+				solved += 1
+			}
+		}
+		status += ", " + strconv.Itoa(solved) + " solved"
+	}
+	screen.DrawTextCenteredAt(status, 24, 640, TURN_TEXT_Y+45, color.White)
+}
+
+// DrawPuzzleResult lists the value of every action, with the answer
+// highlighted and the player's move marked.
+func (g *GameScene) DrawPuzzleResult(screen *ui.ScaledScreen) {
+	screen.DrawUnfilledRect(640-120, 500-20, 240, 40, 2, color.White)
+	screen.DrawTextCenteredAt("Next puzzle", 32, 640, 500, color.White)
+	screen.DrawUnfilledRect(640-120, 550-20, 240, 40, 2, color.White)
+	screen.DrawTextCenteredAt("Try again", 32, 640, 550, color.White)
+	screen.DrawUnfilledRect(640-120, 600-20, 240, 40, 2, color.White)
+	screen.DrawTextCenteredAt("Return to menu", 32, 640, 600, color.White)
+
+	p := g.Puzzle()
+	for i, v := range p.Values {
+		c := color.Color(color.White)
+		if v.Action == p.Answer {
+			c = HintColor
+		}
+		text := v.Action + ": " + formatHintValue(v.Value)
+		if v.Action == g.PuzzleChoice.String() {
+			text += " (your move)"
+		}
+		x := 640 + (float64(i%4)-1.5)*170
+		screen.DrawTextCenteredAt(text, 20, x, 660+float64(i/4)*24, c)
+	}
+	if p.Note != "" {
+		screen.DrawTextCenteredAt(p.Note, 24, 640, 450, color.White)
+	}
+}
+
+func (g *GameScene) UpdatePuzzleResult(cx, cy float64) {
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	index := -1
+	if util.XYinRect(cx, cy, 640-120, 500-20, 240, 40) {
+		index = (g.PuzzleIndex + 1) % len(g.Puzzles)
+	} else if util.XYinRect(cx, cy, 640-120, 550-20, 240, 40) {
+		index = g.PuzzleIndex
+	} else if util.XYinRect(cx, cy, 640-120, 600-20, 240, 40) {
+		g.SceneManager.SwitchToScene("menu")
+		return
+	}
+	if index == -1 {
+		return
+	}
+	gs, err := NewPuzzleGameScene(g.Puzzles, index, g.PuzzleProgress, g.AudioContext)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	g.SceneManager.AddScene("game", gs)
+	g.SceneManager.SwitchToScene("game")
+}
@@ -0,0 +1,360 @@
+package scene
+
+import (
+	"image/color"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/res"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+// RecentGames holds the games finished this session, oldest first, so they
+// can still be replayed after leaving the game.
+var RecentGames []core.GameRecord
+
+const MAX_RECENT_GAMES = 10
+
+func RememberGame(r core.GameRecord) {
+	RecentGames = append(RecentGames, r)
+	if len(RecentGames) > MAX_RECENT_GAMES {
+		RecentGames = RecentGames[1:]
+	}
+}
+
+var REPLAY_SPEEDS = []float64{0.5, 1, 2, 4}
+
+// frame counts at normal speed, the same as the computer's moves in GameScene
+const REPLAY_DRAW_FRAMES = 35
+const REPLAY_PLAY_FRAMES = 50
+const REPLAY_PAUSE_FRAMES = 30
+
+const REPLAY_CONTROLS_Y = 530
+const REPLAY_SPEED_Y = 585
+const REPLAY_BUTTON_W = 70
+const REPLAY_BUTTON_SPACING = 80
+
+const TIMELINE_X = 100
+const TIMELINE_Y = 675
+const TIMELINE_W = 1080
+
+var TimelineColor = color.RGBA{0xb0, 0xc4, 0xb2, 0xff}
+
+type ReplayScene struct {
+	BaseScene
+
+	Record      core.GameRecord
+	History     []core.Move
+	Code        string // share code of the game, empty if it has none
+	PlayerNames [2]string
+
+	Archive      []*core.ArchivedGame // games to page through, nil for a single game
+	ArchiveIndex int
+
+	Step          int // number of moves applied to the board shown
+	Game          *core.Game
+	P0Sprites     [10]*ui.CardSprite
+	P1Sprites     [10]*ui.CardSprite
+	DiscardSprite *ui.CardSprite
+	SecondSprite  *ui.CardSprite
+
+	MovingSprite *ui.CardSprite
+	Animation    ui.Anim
+	Playing      bool
+	SpeedIndex   int
+	PauseTicks   int // ticks to wait before the next move while playing
+
+	HexMap      *ebiten.Image
+	BaseTile    *ebiten.Image
+	Shadow      *ebiten.Image
+	OutlineTile *ebiten.Image
+}
+
+func NewReplayScene(record core.GameRecord) *ReplayScene {
+	r := &ReplayScene{
+		Record:      record,
+		History:     record.History,
+		PlayerNames: [2]string{core.DefaultPlayerName(0), core.DefaultPlayerName(1)},
+		SpeedIndex:  1,
+		HexMap:      res.GetImage("hexmap"),
+		BaseTile:    res.GetImage("basetile"),
+		Shadow:      res.GetImage("shadow"),
+		OutlineTile: res.GetImage("hexoutlinebroken"),
+	}
+	r.Code, _ = core.ShareCode(record)
+	r.SetStep(0)
+	return r
+}
+
+// NewArchiveReplayScene replays the games of an archive, starting with the
+// last one.
+func NewArchiveReplayScene(games []*core.ArchivedGame) *ReplayScene {
+	r := NewReplayScene(games[len(games)-1].Record)
+	r.Archive = games
+	r.ShowArchived(len(games) - 1)
+	return r
+}
+
+// ShowArchived switches to game i of the archive, from its first move.
+func (r *ReplayScene) ShowArchived(i int) {
+	i = util.Clamp(i, 0, len(r.Archive)-1)
+	a := r.Archive[i]
+	r.ArchiveIndex = i
+	r.Record = a.Record
+	r.History = a.Record.History
+	for p, name := range a.Players {
+		if name == "" {
+			name = core.DefaultPlayerName(p)
+		}
+		r.PlayerNames[p] = name
+	}
+	r.Code, _ = core.ShareCode(a.Record)
+	r.Playing = false
+	r.SetStep(0)
+}
+
+// SetStep shows the board after the first step moves, stopping any move that
+// is being animated.
+func (r *ReplayScene) SetStep(step int) {
+	step = util.Clamp(step, 0, len(r.History))
+	g, err := r.Record.Replay(step)
+	if err != nil {
+		return
+	}
+	r.Step = step
+	r.Game = g
+	r.P0Sprites = PyramidSprites(g.Pyramid1, &P0XLocs, &P0YLocs)
+	r.P1Sprites = PyramidSprites(g.Pyramid2, &P1XLocs, &P1YLocs)
+	r.DiscardSprite = r.discardSprite(1)
+	r.SecondSprite = r.discardSprite(2)
+	r.MovingSprite = nil
+	r.Animation = nil
+}
+
+// discardSprite is the card n from the top of the discard stack, or nil.
+func (r *ReplayScene) discardSprite(n int) *ui.CardSprite {
+	if l := len(r.Game.Discards); l >= n {
+		return ui.NewCardSprite(r.Game.Discards[l-n], DISCARD_X, DISCARD_Y)
+	}
+	return nil
+}
+
+func (r *ReplayScene) frames(n int) int {
+	return max(int(float64(n)/REPLAY_SPEEDS[r.SpeedIndex]), 2)
+}
+
+// StartMove animates the next move. The board is rebuilt from the record once
+// the card lands.
+func (r *ReplayScene) StartMove() {
+	if r.Step >= len(r.History) {
+		r.Playing = false
+		return
+	}
+	m := r.History[r.Step]
+	complete := func() {
+		r.Animation = nil
+		r.SetStep(r.Step + 1)
+		r.PauseTicks = r.frames(REPLAY_PAUSE_FRAMES)
+	}
+	if m.EventType == core.DRAW_CARDS {
+		r.MovingSprite = ui.NewCardSprite(m.Card, DECK_BUTTON_X, DECK_BUTTON_Y)
+		r.Animation = ui.NewLinearPathAnimator(r.MovingSprite, r.frames(REPLAY_DRAW_FRAMES),
+			ui.Location{X: DECK_BUTTON_X, Y: DECK_BUTTON_Y},
+			ui.Location{X: DISCARD_X, Y: DISCARD_Y}, ui.EaseOutCubic, complete)
+		return
+	}
+
+	xs, ys := &P0XLocs, &P0YLocs
+	if m.Player == 1 {
+		xs, ys = &P1XLocs, &P1YLocs
+	}
+	r.MovingSprite = r.DiscardSprite
+	r.DiscardSprite = r.SecondSprite
+	r.SecondSprite = r.discardSprite(3)
+	if m.Target >= 6 {
+		r.MovingSprite.ShadowType = 2
+	}
+	r.Animation = ui.NewLinearPathAnimator(r.MovingSprite, r.frames(REPLAY_PLAY_FRAMES),
+		ui.Location{X: DISCARD_X, Y: DISCARD_Y},
+		ui.Location{X: xs[m.Target], Y: ys[m.Target] - ui.TILE_HEIGHT}, ui.EaseOutCubic, complete)
+}
+
+func (r *ReplayScene) TogglePlaying() {
+	r.Playing = !r.Playing
+	if r.Playing && r.Step >= len(r.History) {
+		r.SetStep(0)
+	}
+	r.PauseTicks = 0
+}
+
+// StepTo jumps to a move and pauses playback.
+func (r *ReplayScene) StepTo(step int) {
+	r.Playing = false
+	r.SetStep(step)
+}
+
+func (r *ReplayScene) ChangeSpeed(d int) {
+	r.SpeedIndex = util.Clamp(r.SpeedIndex+d, 0, len(REPLAY_SPEEDS)-1)
+}
+
+func replayButtonX(i int) float64 {
+	return 640 + float64(i-2)*REPLAY_BUTTON_SPACING
+}
+
+func (r *ReplayScene) timelineX(step int) float64 {
+	return TIMELINE_X + TIMELINE_W*float64(step)/float64(max(len(r.History), 1))
+}
+
+func (r *ReplayScene) Update() {
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		r.TogglePlaying()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+		r.StepTo(r.Step - 1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		r.StepTo(r.Step + 1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyHome) {
+		r.StepTo(0)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyEnd) {
+		r.StepTo(len(r.History))
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		r.ChangeSpeed(1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		r.ChangeSpeed(-1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) && r.Archive != nil {
+		r.ShowArchived(r.ArchiveIndex - 1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) && r.Archive != nil {
+		r.ShowArchived(r.ArchiveIndex + 1)
+	}
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		cx, cy := ui.AdjustedCursorPosition()
+		button := -1
+		for i := range 5 {
+			if util.XYinRect(cx, cy, replayButtonX(i)-REPLAY_BUTTON_W/2, REPLAY_CONTROLS_Y-20, REPLAY_BUTTON_W, 40) {
+				button = i
+			}
+		}
+		switch button {
+		case 0:
+			r.StepTo(0)
+		case 1:
+			r.StepTo(r.Step - 1)
+		case 2:
+			r.TogglePlaying()
+		case 3:
+			r.StepTo(r.Step + 1)
+		case 4:
+			r.StepTo(len(r.History))
+		}
+		if util.XYinRect(cx, cy, 640-80, REPLAY_SPEED_Y-20, 160, 40) {
+			r.SpeedIndex = (r.SpeedIndex + 1) % len(REPLAY_SPEEDS)
+		} else if util.XYinRect(cx, cy, TIMELINE_X-10, TIMELINE_Y-15, TIMELINE_W+20, 30) {
+			step := int((cx-TIMELINE_X)/TIMELINE_W*float64(len(r.History)) + 0.5)
+			r.StepTo(step)
+		} else if util.XYinRect(cx, cy, RULES_X-10, RULES_Y-10, 140, 35) {
+			r.SceneManager.SwitchToScene("menu")
+		} else if r.Archive != nil && util.XYinRect(cx, cy, 640-200, RULES_Y-15, 40, 30) {
+			r.ShowArchived(r.ArchiveIndex - 1)
+		} else if r.Archive != nil && util.XYinRect(cx, cy, 640+160, RULES_Y-15, 40, 30) {
+			r.ShowArchived(r.ArchiveIndex + 1)
+		}
+	}
+
+	if r.Animation != nil {
+		r.Animation.Update()
+	} else if r.Playing {
+		if r.PauseTicks > 0 {
+			r.PauseTicks -= 1
+		} else {
+			r.StartMove()
+		}
+	}
+}
+
+func (r *ReplayScene) Draw(screen *ui.ScaledScreen) {
+	screen.Screen.Fill(color.RGBA{0x44, 0x5c, 0x47, 0xff})
+	screen.DrawText("Back to menu", 18, RULES_X, RULES_Y, color.White)
+	if r.Code != "" {
+		screen.DrawText("Game code: "+r.Code, 16, 20, RULES_Y, color.White)
+	}
+	if r.Archive != nil {
+		text := "Game " + strconv.Itoa(r.ArchiveIndex+1) + " of " + strconv.Itoa(len(r.Archive))
+		screen.DrawTextCenteredAt("<", 22, 640-180, RULES_Y, color.White)
+		screen.DrawTextCenteredAt(text, 22, 640, RULES_Y, color.White)
+		screen.DrawTextCenteredAt(">", 22, 640+180, RULES_Y, color.White)
+	}
+
+	for _, start := range []float64{P0StartX, P1StartX} {
+		opts := &ebiten.DrawImageOptions{}
+		opts.GeoM.Translate(start, P0StartY)
+		screen.DrawImage(r.HexMap, opts)
+	}
+	screen.DrawTextCenteredAt("Score: "+strconv.Itoa(r.Game.Pyramid1.Score()), 36, P0StartX+ui.TILE_X_OFFSET*1.5, P0StartY-40, color.White)
+	screen.DrawTextCenteredAt("Score: "+strconv.Itoa(r.Game.Pyramid2.Score()), 36, P1StartX+ui.TILE_X_OFFSET*1.5, P1StartY-40, color.White)
+
+	deckOpts := &ebiten.DrawImageOptions{}
+	deckOpts.GeoM.Translate(DECK_BUTTON_X, DECK_BUTTON_Y)
+	screen.DrawImage(r.BaseTile, deckOpts)
+	deckShadowOpts := &ebiten.DrawImageOptions{}
+	deckShadowOpts.GeoM.Translate(DECK_BUTTON_X, DECK_BUTTON_Y)
+	screen.DrawImage(r.Shadow, deckShadowOpts)
+	screen.DrawTextCenteredAt("Deck:", 30, DECK_BUTTON_X+ui.TILE_X_OFFSET/2, DECK_BUTTON_Y-50, color.White)
+	screen.DrawTextCenteredAt("Revealed:", 30, DISCARD_X+ui.TILE_X_OFFSET/2, DISCARD_Y-50, color.White)
+
+	dOpts := &ebiten.DrawImageOptions{}
+	dOpts.GeoM.Translate(DISCARD_X, DISCARD_Y+ui.TILE_HEIGHT)
+	screen.DrawImage(r.OutlineTile, dOpts)
+	if r.SecondSprite != nil {
+		r.SecondSprite.Draw(screen)
+	}
+	if r.DiscardSprite != nil {
+		r.DiscardSprite.Draw(screen)
+	}
+
+	for _, sprites := range [][10]*ui.CardSprite{r.P0Sprites, r.P1Sprites} {
+		for _, s := range sprites {
+			if s != nil {
+				s.Draw(screen)
+			}
+		}
+	}
+	if r.MovingSprite != nil {
+		r.MovingSprite.Draw(screen)
+	}
+
+	if r.Step < len(r.History) {
+		screen.DrawTextCenteredAt("Move "+strconv.Itoa(r.Step+1)+" of "+strconv.Itoa(len(r.History)), 36, 640, 60, color.White)
+		screen.DrawTextCenteredAt(r.History[r.Step].Describe(r.PlayerNames), 30, 640, 110, color.White)
+	} else {
+		screen.DrawTextCenteredAt("Final position", 36, 640, 60, color.White)
+		screen.DrawTextCenteredAt("Final score "+strconv.Itoa(r.Game.Pyramid1.Score())+" - "+strconv.Itoa(r.Game.Pyramid2.Score()), 30, 640, 110, color.White)
+	}
+
+	playLabel := "Play"
+	if r.Playing {
+		playLabel = "Pause"
+	}
+	for i, label := range []string{"|<", "<", playLabel, ">", ">|"} {
+		x := replayButtonX(i)
+		screen.DrawUnfilledRect(x-REPLAY_BUTTON_W/2, REPLAY_CONTROLS_Y-20, REPLAY_BUTTON_W, 40, 2, color.White)
+		screen.DrawTextCenteredAt(label, 22, x, REPLAY_CONTROLS_Y, color.White)
+	}
+	speed := strconv.FormatFloat(REPLAY_SPEEDS[r.SpeedIndex], 'f', -1, 64)
+	screen.DrawUnfilledRect(640-80, REPLAY_SPEED_Y-20, 160, 40, 2, color.White)
+	screen.DrawTextCenteredAt("Speed: "+speed+"x", 22, 640, REPLAY_SPEED_Y, color.White)
+
+	// plays are marked taller than draws
+	screen.DrawRect(TIMELINE_X, TIMELINE_Y-1, TIMELINE_W, 2, TimelineColor)
+	for i, m := range r.History {
+		x := r.timelineX(i + 1)
+		h := 8.0
+		if m.EventType == core.PLAY_CARD {
+			h = 16
+		}
+		screen.DrawRect(x-1, TIMELINE_Y-h/2, 2, h, TimelineColor)
+	}
+	screen.DrawCircle(r.timelineX(r.Step), TIMELINE_Y, 7, color.White)
+}
@@ -0,0 +1,209 @@
+package scene
+
+import (
+	"image/color"
+	"log"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/res"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+var BlunderColor = color.RGBA{0xff, 0x70, 0x60, 0xff}
+
+const REVIEW_BUTTON_Y = 600
+
+type ReviewScene struct {
+	BaseScene
+
+	Record       core.GameRecord
+	History      []core.Move
+	PlayerNames  [2]string
+	Analysis     *core.GameAnalysis
+	AnalysisErr  error // why the game could not be analyzed
+	analysisChan chan analysisResult
+
+	Step      int // number of moves applied to the board shown
+	Game      *core.Game
+	P0Sprites [10]*ui.CardSprite
+	P1Sprites [10]*ui.CardSprite
+	TopSprite *ui.CardSprite
+
+	HexMap    *ebiten.Image
+	BaseTile  *ebiten.Image
+	Shadow    *ebiten.Image
+	HoverTile *ebiten.Image
+}
+
+func NewReviewScene(record core.GameRecord, model *core.LinearModel) *ReviewScene {
+	r := &ReviewScene{
+		Record:       record,
+		History:      record.History,
+		PlayerNames:  [2]string{core.DefaultPlayerName(0), core.DefaultPlayerName(1)},
+		analysisChan: make(chan analysisResult, 1),
+		HexMap:       res.GetImage("hexmap"),
+		BaseTile:     res.GetImage("basetile"),
+		Shadow:       res.GetImage("shadow"),
+		HoverTile:    HoverTile(),
+	}
+	r.SetStep(0)
+	go func() {
+		a, err := core.AnalyzeGame(record, model)
+		if err != nil {
+			log.Println("analysis:", err)
+		}
+		r.analysisChan <- analysisResult{a, err}
+	}()
+	return r
+}
+
+type analysisResult struct {
+	analysis *core.GameAnalysis
+	err      error
+}
+
+func (r *ReviewScene) SetStep(step int) {
+	step = util.Clamp(step, 0, len(r.History))
+	g, err := r.Record.Replay(step)
+	if err != nil {
+		return
+	}
+	r.Step = step
+	r.Game = g
+	r.P0Sprites = PyramidSprites(g.Pyramid1, &P0XLocs, &P0YLocs)
+	r.P1Sprites = PyramidSprites(g.Pyramid2, &P1XLocs, &P1YLocs)
+	r.TopSprite = nil
+	if c := g.TopDiscard(); c != nil {
+		r.TopSprite = ui.NewCardSprite(c, DISCARD_X, DISCARD_Y)
+	}
+}
+
+// NextBlunder moves to the next flagged move after the current one.
+func (r *ReviewScene) NextBlunder() {
+	if r.Analysis == nil {
+		return
+	}
+	for i := r.Step + 1; i < len(r.Analysis.Moves); i++ {
+		if r.Analysis.Moves[i].Blunder {
+			r.SetStep(i)
+			return
+		}
+	}
+}
+
+func (r *ReviewScene) Update() {
+	select {
+	case a := <-r.analysisChan:
+		r.Analysis, r.AnalysisErr = a.analysis, a.err
+	default:
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+		r.SetStep(r.Step - 1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		r.SetStep(r.Step + 1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		r.NextBlunder()
+	}
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		cx, cy := ui.AdjustedCursorPosition()
+		if util.XYinRect(cx, cy, 640-300-80, REVIEW_BUTTON_Y-20, 160, 40) {
+			r.SetStep(r.Step - 1)
+		} else if util.XYinRect(cx, cy, 640-80, REVIEW_BUTTON_Y-20, 160, 40) {
+			r.NextBlunder()
+		} else if util.XYinRect(cx, cy, 640+300-80, REVIEW_BUTTON_Y-20, 160, 40) {
+			r.SetStep(r.Step + 1)
+		} else if util.XYinRect(cx, cy, 640-120, 670-20, 240, 40) {
+			r.SceneManager.SwitchToScene("menu")
+		}
+	}
+}
+
+func (r *ReviewScene) Draw(screen *ui.ScaledScreen) {
+	screen.Screen.Fill(color.RGBA{0x44, 0x5c, 0x47, 0xff})
+
+	for _, start := range []float64{P0StartX, P1StartX} {
+		opts := &ebiten.DrawImageOptions{}
+		opts.GeoM.Translate(start, P0StartY)
+		screen.DrawImage(r.HexMap, opts)
+	}
+	screen.DrawTextCenteredAt("Score: "+strconv.Itoa(r.Game.Pyramid1.Score()), 36, P0StartX+ui.TILE_X_OFFSET*1.5, P0StartY-40, color.White)
+	screen.DrawTextCenteredAt("Score: "+strconv.Itoa(r.Game.Pyramid2.Score()), 36, P1StartX+ui.TILE_X_OFFSET*1.5, P1StartY-40, color.White)
+
+	deckOpts := &ebiten.DrawImageOptions{}
+	deckOpts.GeoM.Translate(DECK_BUTTON_X, DECK_BUTTON_Y)
+	screen.DrawImage(r.BaseTile, deckOpts)
+	deckShadowOpts := &ebiten.DrawImageOptions{}
+	deckShadowOpts.GeoM.Translate(DECK_BUTTON_X, DECK_BUTTON_Y)
+	screen.DrawImage(r.Shadow, deckShadowOpts)
+	screen.DrawTextCenteredAt("Deck:", 30, DECK_BUTTON_X+ui.TILE_X_OFFSET/2, DECK_BUTTON_Y-50, color.White)
+	screen.DrawTextCenteredAt("Revealed:", 30, DISCARD_X+ui.TILE_X_OFFSET/2, DISCARD_Y-50, color.White)
+	if r.TopSprite != nil {
+		r.TopSprite.Draw(screen)
+	}
+
+	for _, sprites := range [][10]*ui.CardSprite{r.P0Sprites, r.P1Sprites} {
+		for _, s := range sprites {
+			if s != nil {
+				s.Draw(screen)
+			}
+		}
+	}
+
+	if r.Step < len(r.History) {
+		m := r.History[r.Step]
+		screen.DrawTextCenteredAt("Move "+strconv.Itoa(r.Step+1)+" of "+strconv.Itoa(len(r.History)), 36, 640, 60, color.White)
+		if m.EventType == core.DRAW_CARDS {
+			screen.DrawUnfilledRect(DECK_BUTTON_X-4, DECK_BUTTON_Y-4, DECK_BUTTON_W+8, DECK_BUTTON_H+8, 3, color.White)
+		} else {
+			xs, ys := &P0XLocs, &P0YLocs
+			if m.Player == 1 {
+				xs, ys = &P1XLocs, &P1YLocs
+			}
+			opt := &ebiten.DrawImageOptions{}
+			opt.GeoM.Translate(xs[m.Target], ys[m.Target])
+			screen.DrawImage(r.HoverTile, opt)
+		}
+		screen.DrawTextCenteredAt(m.Describe(r.PlayerNames), 30, 640, 110, color.White)
+	} else {
+		screen.DrawTextCenteredAt("Final position", 30, 640, 110, color.White)
+	}
+
+	if r.AnalysisErr != nil {
+		screen.DrawTextCenteredAt("This game could not be analyzed", 24, 640, 160, BlunderColor)
+	} else if r.Analysis == nil {
+		screen.DrawTextCenteredAt("Analyzing...", 24, 640, 160, color.White)
+	} else {
+		if r.Step < len(r.Analysis.Moves) {
+			ma := r.Analysis.Moves[r.Step]
+			text := "Best: " + ma.Best.Event.String() + " (" + formatHintValue(ma.Best.Value) + ")   Loss: " + formatHintValue(ma.Loss)
+			c := color.Color(color.White)
+			if ma.Blunder {
+				text = "Blunder! " + text
+				c = BlunderColor
+			}
+			screen.DrawTextCenteredAt(text, 24, 640, 160, c)
+		}
+		for p := range 2 {
+			summary := "Expected loss " + formatHintValue(r.Analysis.TotalLoss[p]) + "\n" + strconv.Itoa(r.Analysis.Blunders[p]) + " blunders"
+			x := P0StartX + ui.TILE_X_OFFSET*1.5
+			if p == 1 {
+				x = P1StartX + ui.TILE_X_OFFSET*1.5
+			}
+			screen.DrawTextCenteredAt(summary, 20, x, 200, color.White)
+		}
+	}
+
+	for i, label := range []string{"< Prev", "Next blunder", "Next >"} {
+		x := 640 + float64(i-1)*300
+		screen.DrawUnfilledRect(x-80, REVIEW_BUTTON_Y-20, 160, 40, 2, color.White)
+		screen.DrawTextCenteredAt(label, 24, x, REVIEW_BUTTON_Y, color.White)
+	}
+	screen.DrawUnfilledRect(640-120, 670-20, 240, 40, 2, color.White)
+	screen.DrawTextCenteredAt("Return to menu", 32, 640, 670, color.White)
+}
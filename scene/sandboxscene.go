@@ -0,0 +1,300 @@
+package scene
+
+import (
+	"image/color"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/res"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+// SandboxScene lets any card go in any slot, to try out scores and to build
+// positions. Cards come from a palette holding the two copies of every card.
+type SandboxScene struct {
+	BaseScene
+
+	Pyramids   [2]*core.Pyramid
+	Open       *core.Card // the revealed card of an exported position
+	Sprites    [2][10]*ui.CardSprite
+	OpenSprite *ui.CardSprite
+
+	Drag     *ui.CardSprite // the card being moved, taken out of its place
+	DragFrom int            // SANDBOX_PALETTE, SANDBOX_OPEN or the pyramid
+	DragSlot int
+
+	Message string
+
+	Tiles       *ui.Tileset
+	HexMap      *ebiten.Image
+	HoverTile   *ebiten.Image
+	OutlineTile *ebiten.Image
+}
+
+const (
+	SANDBOX_PALETTE = -1
+	SANDBOX_OPEN    = 2
+)
+
+const PALETTE_X = 640 - 5*PALETTE_STEP
+const PALETTE_Y = 60
+const PALETTE_STEP = 62
+const PALETTE_SCALE = 0.5
+
+const SANDBOX_BUTTON_X = 640
+const SANDBOX_BUTTON_Y = 540
+const SANDBOX_EDGES_Y = 685
+
+func NewSandboxScene() *SandboxScene {
+	s := &SandboxScene{
+		Pyramids:    [2]*core.Pyramid{{}, {}},
+		Tiles:       ui.NewTileset(res.GetImage("hextiletileset"), 120, 146),
+		HexMap:      res.GetImage("hexmap"),
+		HoverTile:   HoverTile(),
+		OutlineTile: res.GetImage("hexoutlinebroken"),
+		Message:     "Drag cards from the top onto either pyramid. Drag a card off or right click it to remove it.",
+	}
+	return s
+}
+
+// remaining counts the copies of card type t not on the board.
+func (s *SandboxScene) remaining(t int) int {
+	n := 2
+	for _, p := range s.Pyramids {
+		for _, c := range p.Cards {
+			if c != nil && core.TypeIndex(c) == t {
+				n -= 1
+			}
+		}
+	}
+	if s.Open != nil && core.TypeIndex(s.Open) == t {
+		n -= 1
+	}
+	if s.Drag != nil && s.DragFrom == SANDBOX_PALETTE && core.TypeIndex(s.Drag.Card) == t {
+		n -= 1
+	}
+	return n
+}
+
+func paletteXY(t int) (float64, float64) {
+	c := core.TypeToCard(t)
+	return PALETTE_X + float64(c.Value-1)*PALETTE_STEP, PALETTE_Y + float64(c.Color)*80
+}
+
+func (s *SandboxScene) refresh() {
+	for p := range 2 {
+		s.Sprites[p] = PyramidSprites(s.Pyramids[p], &DuelLayout.XLocs[p], &DuelLayout.YLocs[p])
+	}
+	s.OpenSprite = nil
+	if s.Open != nil {
+		s.OpenSprite = ui.NewCardSprite(s.Open, DISCARD_X, DISCARD_Y)
+	}
+}
+
+// slotAt finds the slot under the cursor, checking the upper layers first
+// since they sit over the ones below.
+func (s *SandboxScene) slotAt(x, y float64) (int, int) {
+	for p := range 2 {
+		for i := 9; i >= 0; i-- {
+			if XYinHexCell(x, y, DuelLayout.XLocs[p][i], DuelLayout.YLocs[p][i], ui.TILE_SIZE_X, ui.TILE_SIZE_Y-ui.TILE_HEIGHT, ui.TILE_TIP_HEIGHT) {
+				return p, i
+			}
+		}
+	}
+	if util.XYinRect(x, y, DISCARD_X, DISCARD_Y, ui.TILE_SIZE_X, ui.TILE_SIZE_Y) {
+		return SANDBOX_OPEN, 0
+	}
+	return -1, -1
+}
+
+func (s *SandboxScene) cardAt(p, i int) *core.Card {
+	if p == SANDBOX_OPEN {
+		return s.Open
+	}
+	return s.Pyramids[p].Cards[i]
+}
+
+func (s *SandboxScene) setCard(p, i int, c *core.Card) {
+	if p == SANDBOX_OPEN {
+		s.Open = c
+	} else {
+		s.Pyramids[p].Cards[i] = c
+	}
+}
+
+func (s *SandboxScene) startDrag(c *core.Card, from, slot int, cx, cy float64) {
+	s.Drag = ui.NewCardSprite(c, cx-ui.TILE_SIZE_X/2, cy-(ui.TILE_SIZE_Y-ui.TILE_HEIGHT-6)/2)
+	s.Drag.ShadowType = 1
+	s.DragFrom, s.DragSlot = from, slot
+	if from != SANDBOX_PALETTE {
+		s.setCard(from, slot, nil)
+	}
+	s.refresh()
+}
+
+// drop puts the dragged card down. A card moved onto another one swaps with
+// it, a card from the palette replaces it and a card dropped anywhere else is
+// taken off the board.
+func (s *SandboxScene) drop(cx, cy float64) {
+	p, i := s.slotAt(cx, cy)
+	if p != -1 {
+		if old := s.cardAt(p, i); old != nil && s.DragFrom != SANDBOX_PALETTE {
+			s.setCard(s.DragFrom, s.DragSlot, old)
+		}
+		s.setCard(p, i, s.Drag.Card)
+	}
+	s.Drag = nil
+	s.refresh()
+}
+
+func (s *SandboxScene) Clear() {
+	s.Pyramids = [2]*core.Pyramid{{}, {}}
+	s.Open = nil
+	s.Message = ""
+	s.refresh()
+}
+
+// Export writes the board as a position string with the first pyramid's
+// player to move when both have the same number of cards. The position is
+// checked the same way it would be when loaded.
+func (s *SandboxScene) Export() {
+	g := core.NewGame()
+	g.Pyramid1, g.Pyramid2 = s.Pyramids[0], s.Pyramids[1]
+	g.Discards = []*core.Card{}
+	if s.Open != nil {
+		g.Discards = append(g.Discards, s.Open)
+	}
+	g.Turn = g.Pyramid1.Count() + g.Pyramid2.Count()
+	position := g.PublicPosition()
+	if _, err := core.NewGameFromPosition(position, 0); err != nil {
+		s.Message = "Can't export: " + err.Error()
+		return
+	}
+	s.Message = position
+	log.Println("position: " + position)
+}
+
+func (s *SandboxScene) Update() {
+	cx, cy := ui.AdjustedCursorPosition()
+	if s.Drag != nil {
+		s.Drag.X = cx - ui.TILE_SIZE_X/2
+		s.Drag.Y = cy - (ui.TILE_SIZE_Y-ui.TILE_HEIGHT-6)/2
+		if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
+			s.drop(cx, cy)
+		}
+		return
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		s.Clear()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		s.Export()
+	}
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		if p, i := s.slotAt(cx, cy); p != -1 {
+			s.setCard(p, i, nil)
+			s.refresh()
+		}
+	}
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	for t := range 20 {
+		x, y := paletteXY(t)
+		if util.XYinRect(cx, cy, x, y, ui.TILE_SIZE_X*PALETTE_SCALE, 146*PALETTE_SCALE) && s.remaining(t) > 0 {
+			s.startDrag(core.TypeToCard(t), SANDBOX_PALETTE, 0, cx, cy)
+			return
+		}
+	}
+	if p, i := s.slotAt(cx, cy); p != -1 && s.cardAt(p, i) != nil {
+		s.startDrag(s.cardAt(p, i), p, i, cx, cy)
+	} else if util.XYinRect(cx, cy, SANDBOX_BUTTON_X-120, SANDBOX_BUTTON_Y-20, 240, 40) {
+		s.Clear()
+	} else if util.XYinRect(cx, cy, SANDBOX_BUTTON_X-120, SANDBOX_BUTTON_Y+50-20, 240, 40) {
+		s.Export()
+	} else if util.XYinRect(cx, cy, RULES_X-10, RULES_Y-10, 140, 35) {
+		s.SceneManager.SwitchToScene("menu")
+	}
+}
+
+// EdgeSums lists the score of each edge of p, with a dash for edges that are
+// not complete.
+func EdgeSums(p *core.Pyramid) string {
+	parts := make([]string, 6)
+	for i := range 6 {
+		score, _ := p.EdgeScore(i)
+		if p.EdgeComplete(i) {
+			parts[i] = strconv.Itoa(score)
+		} else {
+			parts[i] = "-"
+		}
+	}
+	return "Edges " + strings.Join(parts, " + ") + " = " + strconv.Itoa(p.Score())
+}
+
+func (s *SandboxScene) Draw(screen *ui.ScaledScreen) {
+	screen.Screen.Fill(color.RGBA{0x44, 0x5c, 0x47, 0xff})
+	screen.DrawText("Back to menu", 18, RULES_X, RULES_Y, color.White)
+	screen.DrawText("Sandbox", 32, 20, RULES_Y, color.White)
+
+	for t := range 20 {
+		x, y := paletteXY(t)
+		c := core.TypeToCard(t)
+		n := s.remaining(t)
+		opts := &ebiten.DrawImageOptions{}
+		opts.GeoM.Scale(PALETTE_SCALE, PALETTE_SCALE)
+		opts.GeoM.Translate(x, y)
+		if n == 0 {
+			opts.ColorScale.ScaleAlpha(0.3)
+		}
+		screen.DrawImage(s.Tiles.TileAtIJ(c.Value-1, c.Color*4), opts)
+		screen.DrawTextCenteredAt("x"+strconv.Itoa(n), 14, x+50, y+62, color.White)
+	}
+
+	for p := range 2 {
+		opts := &ebiten.DrawImageOptions{}
+		opts.GeoM.Translate(DuelLayout.StartX[p], DuelLayout.StartY)
+		screen.DrawImage(s.HexMap, opts)
+		center := DuelLayout.StartX[p] + ui.TILE_X_OFFSET*1.5
+		screen.DrawTextCenteredAt("Score: "+strconv.Itoa(s.Pyramids[p].Score()), 36, center, DuelLayout.StartY-40, color.White)
+		screen.DrawTextCenteredAt(EdgeSums(s.Pyramids[p]), 20, center, SANDBOX_EDGES_Y, color.White)
+	}
+
+	screen.DrawTextCenteredAt("Open card:", 30, DISCARD_X+ui.TILE_X_OFFSET/2, DISCARD_Y-50, color.White)
+	openOpts := &ebiten.DrawImageOptions{}
+	openOpts.GeoM.Translate(DISCARD_X, DISCARD_Y+ui.TILE_HEIGHT)
+	screen.DrawImage(s.OutlineTile, openOpts)
+	if s.OpenSprite != nil {
+		s.OpenSprite.Draw(screen)
+	}
+
+	for _, sprites := range s.Sprites {
+		for _, sp := range sprites {
+			if sp != nil {
+				sp.Draw(screen)
+			}
+		}
+	}
+
+	if s.Drag != nil {
+		cx, cy := ui.AdjustedCursorPosition()
+		if p, i := s.slotAt(cx, cy); p != -1 && p != SANDBOX_OPEN {
+			opts := &ebiten.DrawImageOptions{}
+			opts.GeoM.Translate(DuelLayout.XLocs[p][i], DuelLayout.YLocs[p][i])
+			screen.DrawImage(s.HoverTile, opts)
+		}
+		s.Drag.Draw(screen)
+	}
+
+	screen.DrawUnfilledRect(SANDBOX_BUTTON_X-120, SANDBOX_BUTTON_Y-20, 240, 40, 2, color.White)
+	screen.DrawTextCenteredAt("Clear (C)", 28, SANDBOX_BUTTON_X, SANDBOX_BUTTON_Y, color.White)
+	screen.DrawUnfilledRect(SANDBOX_BUTTON_X-120, SANDBOX_BUTTON_Y+50-20, 240, 40, 2, color.White)
+	screen.DrawTextCenteredAt("Export position (E)", 28, SANDBOX_BUTTON_X, SANDBOX_BUTTON_Y+50, color.White)
+	screen.DrawTextCenteredAt(wrapText(s.Message, 48), 16, 640, 640, color.White)
+}
@@ -0,0 +1,127 @@
+package scene
+
+import (
+	"image/color"
+	"strconv"
+
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/ui"
+)
+
+const EDGES_X = 1150
+const EDGES_Y = 90
+
+// EDGE_OFFSETS shift each edge's line off the middle of its cards, the same
+// way the edge guide in the rules does, so edges that share a card don't draw
+// over each other.
+var EDGE_OFFSETS = [6][2]float64{
+	{-22, 12},
+	{22, 12},
+	{0, 35},
+	{0, 0},
+	{-22, -18},
+	{22, -18},
+}
+
+var ZeroEdgeColor = color.RGBA{0x90, 0x90, 0x90, 0xff}
+var EdgeOutlineColor = color.RGBA{0x22, 0x22, 0x22, 0xff}
+
+// EdgeColor fades from white for an edge worth 1 to orange for one worth 10.
+// Edges that score nothing are grey.
+func EdgeColor(score int) color.Color {
+	if score == 0 {
+		return ZeroEdgeColor
+	}
+	t := float64(score-1) / 9
+	return color.RGBA{0xff, uint8(0xff - t*0x5f), uint8(0xff - t*0xcf), 0xff}
+}
+
+// edgePoint is where edge e crosses the card in the slot at x, y.
+func edgePoint(x, y float64, e int) (float64, float64) {
+	return x + ui.TILE_SIZE_X/2 + EDGE_OFFSETS[e][0], y - ui.TILE_HEIGHT + 60 + EDGE_OFFSETS[e][1]
+}
+
+// landed reports whether the card in slot i of player p's pyramid has
+// finished moving into place.
+func (g *GameScene) landed(p, i int) bool {
+	s := g.P0Spheres[i]
+	if p == 1 {
+		s = g.P1Spheres[i]
+	}
+	return s != nil && s.X == g.Layout.XLocs[p][i] && s.Y == g.Layout.YLocs[p][i]-ui.TILE_HEIGHT
+}
+
+// DrawEdges draws every complete edge of player p's pyramid in the color of
+// its score, with a larger dot on the card that scored it. pend is a slot
+// the dragged card is being held over, or -1.
+func (g *GameScene) DrawEdges(screen *ui.ScaledScreen, p int, pend int) {
+	pyramid := *g.Game.Pyramid1
+	if p == 1 {
+		pyramid = *g.Game.Pyramid2
+	}
+	if pend != -1 {
+		pyramid.Cards[pend] = g.DragSprite.Card
+	}
+	for e, edge := range core.Edges {
+		if !pyramid.EdgeComplete(e) {
+			continue
+		}
+		ready := true
+		for _, i := range edge {
+			ready = ready && (i == pend || g.landed(p, i))
+		}
+		if !ready {
+			continue
+		}
+		score, slot := pyramid.EdgeScore(e)
+		c := EdgeColor(score)
+		x1, y1 := edgePoint(g.Layout.XLocs[p][edge[0]], g.Layout.YLocs[p][edge[0]], e)
+		x2, y2 := edgePoint(g.Layout.XLocs[p][edge[2]], g.Layout.YLocs[p][edge[2]], e)
+		screen.DrawLine(x1, y1, x2, y2, 3, c)
+		for _, i := range edge {
+			x, y := edgePoint(g.Layout.XLocs[p][i], g.Layout.YLocs[p][i], e)
+			if i == slot {
+				screen.DrawCircle(x, y, 10, EdgeOutlineColor)
+				screen.DrawCircle(x, y, 8, c)
+			} else {
+				screen.DrawCircle(x, y, 5, c)
+			}
+		}
+	}
+}
+
+// DrawPlacementDeltas shows how many points the dragged card would add in
+// each slot it can go in.
+func (g *GameScene) DrawPlacementDeltas(screen *ui.ScaledScreen) {
+	for i := range 10 {
+		pyramid, x, y := g.PyramidXYForTurn(i)
+		if !pyramid.CanPlace(i) {
+			continue
+		}
+		delta := pyramid.TentativeScoreWithCard(g.DragSprite.Card, i) - pyramid.Score()
+		c := color.Color(color.White)
+		if delta > 0 {
+			c = HintColor
+		}
+		screen.DrawTextCenteredAt("+"+strconv.Itoa(delta), 24, x+ui.TILE_SIZE_X/2, y+(ui.TILE_SIZE_Y-ui.TILE_HEIGHT)/2+28, c)
+	}
+}
+
+// DrawScoreOverlay explains the scores on the board: the edges of both
+// pyramids and, while a card is being dragged, what each slot is worth.
+func (g *GameScene) DrawScoreOverlay(screen *ui.ScaledScreen) {
+	dragging := g.DragSprite != nil && g.UIState == WAITING_FOR_PLAYER_MOVE
+	for p := range 2 {
+		if p == 1 && g.Game.Solo {
+			break
+		}
+		pend := -1
+		if dragging && p == g.Game.CurrentPlayer() {
+			pend = g.PendIndex
+		}
+		g.DrawEdges(screen, p, pend)
+	}
+	if dragging {
+		g.DrawPlacementDeltas(screen)
+	}
+}
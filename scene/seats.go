@@ -0,0 +1,165 @@
+package scene
+
+import (
+	"image/color"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+// SEAT_AGENTS are the choices for a seat, a human and then the computer
+// players weakest first.
+var SEAT_AGENTS = append([]string{""}, OPPONENTS...)
+
+const SEAT_X = 170 // how far each seat is from the center
+const SEAT_ARROW_X = 130
+
+func seatX(seat int) float64 {
+	if seat == 0 {
+		return CENTER - SEAT_X
+	}
+	return CENTER + SEAT_X
+}
+
+func seatAgentLabel(agent string) string {
+	if agent == "" {
+		return "Human"
+	}
+	return OPPONENT_LABELS[agent]
+}
+
+// nextName steps d places through the saved names for seat, skipping the
+// name in the other seat.
+func (m *MenuScene) nextName(seat, d int) string {
+	names := m.Profiles.Names()
+	if indexOf(names, m.SeatNames[seat]) == -1 {
+		names = append(names, m.SeatNames[seat])
+	}
+	name := m.SeatNames[seat]
+	for range names {
+		name = cycle(names, name, d)
+		if name != m.SeatNames[1-seat] {
+			return name
+		}
+	}
+	return m.SeatNames[seat]
+}
+
+// SetSeatName puts the player called name in seat and remembers it for next
+// time, adding a profile if they don't have one.
+func (m *MenuScene) SetSeatName(seat int, name string) {
+	m.SeatNames[seat] = name
+	// read the profiles again, since games may have added to them
+	m.UpdateProfiles()
+	m.Profiles.Get(name)
+	m.Profiles.Seats = m.SeatNames
+	if err := m.Profiles.Save(); err != nil {
+		log.Println(err)
+	}
+}
+
+func (m *MenuScene) UpdateProfiles() {
+	profiles, err := core.LoadProfiles()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	m.Profiles = profiles
+}
+
+// UpdateSeats handles clicks on the seat set up and reports whether there
+// was one. The arrows beside each seat step through the kinds of player and
+// the saved names, and clicking a name types a new one.
+func (m *MenuScene) UpdateSeats(cx, cy float64) bool {
+	for seat := range 2 {
+		x := seatX(seat)
+		for d := -1; d <= 1; d += 2 {
+			ax := x + float64(d)*SEAT_ARROW_X
+			if util.XYinRect(cx, cy, ax-20, CHOICE_HEADER_Y+40-20, 40, 40) {
+				m.SeatAgents[seat] = cycle(SEAT_AGENTS, m.SeatAgents[seat], d)
+				return true
+			}
+			if m.SeatAgents[seat] == "" && util.XYinRect(cx, cy, ax-20, CHOICE_HEADER_Y+80-20, 40, 40) {
+				m.SetSeatName(seat, m.nextName(seat, d))
+				return true
+			}
+		}
+		if m.SeatAgents[seat] == "" && util.XYinRect(cx, cy, x-SEAT_ARROW_X+20, CHOICE_HEADER_Y+80-20, 2*SEAT_ARROW_X-40, 40) {
+			m.EditingSeat = seat
+			m.NameText = ""
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MenuScene) DrawSeats(screen *ui.ScaledScreen) {
+	for seat := range 2 {
+		x := seatX(seat)
+		screen.DrawTextCenteredAt("Seat "+[]string{"1", "2"}[seat], 32.0, x, CHOICE_HEADER_Y, color.White)
+		screen.DrawTextCenteredAt("<", 24.0, x-SEAT_ARROW_X, CHOICE_HEADER_Y+40, color.White)
+		screen.DrawTextCenteredAt(seatAgentLabel(m.SeatAgents[seat]), 24.0, x, CHOICE_HEADER_Y+40, color.White)
+		screen.DrawTextCenteredAt(">", 24.0, x+SEAT_ARROW_X, CHOICE_HEADER_Y+40, color.White)
+		if m.SeatAgents[seat] == "" {
+			screen.DrawTextCenteredAt("<", 24.0, x-SEAT_ARROW_X, CHOICE_HEADER_Y+80, color.White)
+			screen.DrawTextCenteredAt(m.SeatNames[seat], 24.0, x, CHOICE_HEADER_Y+80, HintColor)
+			screen.DrawTextCenteredAt(">", 24.0, x+SEAT_ARROW_X, CHOICE_HEADER_Y+80, color.White)
+		}
+	}
+	if m.SeatAgents[0] == "" || m.SeatAgents[1] == "" {
+		screen.DrawTextCenteredAt("Click a name to type a new one", 16.0, CENTER, CHOICE_HEADER_Y+115, color.White)
+	}
+}
+
+// Typing reports whether the menu is taking typed text, a game code or a
+// name, so keys shouldn't be read as shortcuts.
+func (m *MenuScene) Typing() bool {
+	return m.EnteringCode || m.EditingSeat != -1
+}
+
+func (m *MenuScene) UpdateNameEntry() {
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if (unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || r == '-' || r == '\'') && utf8.RuneCountInString(m.NameText) < core.MAX_NAME_LENGTH {
+			m.NameText += string(r)
+			m.NameError = ""
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(m.NameText) > 0 {
+		_, size := utf8.DecodeLastRuneInString(m.NameText)
+		m.NameText = m.NameText[:len(m.NameText)-size]
+		m.NameError = ""
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		name := strings.TrimSpace(m.NameText)
+		if name == "" {
+			m.NameError = "Type a name first."
+		} else if name == m.SeatNames[1-m.EditingSeat] {
+			m.NameError = name + " is already in the other seat."
+		} else {
+			m.SetSeatName(m.EditingSeat, name)
+			m.EditingSeat = -1
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		m.EditingSeat = -1
+		m.NameError = ""
+	}
+}
+
+func (m *MenuScene) DrawNameEntry(screen *ui.ScaledScreen) {
+	title := "Player name for seat " + []string{"1", "2"}[m.EditingSeat]
+	screen.DrawTextCenteredAt(title, 48.0, CENTER, TITLE_Y_CENTER, color.White)
+	screen.DrawTextCenteredAt("Type a name, then press Enter. Esc to go back.", 24.0, CENTER, CHOICE_HEADER_Y, color.White)
+	screen.DrawUnfilledRect(CENTER-300, 380-30, 600, 60, 2, color.White)
+	screen.DrawTextCenteredAt(m.NameText+"_", 28.0, CENTER, 380, color.White)
+	if m.NameError != "" {
+		screen.DrawTextCenteredAt(m.NameError, 24.0, CENTER, 460, BlunderColor)
+	}
+}
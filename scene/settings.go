@@ -0,0 +1,219 @@
+package scene
+
+import (
+	"image/color"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/res"
+	"github.com/prizelobby/pyramid-rummy/storage"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+var SETTINGS_KEY = storage.Key{Namespace: "player", Name: "settings", Version: 1}
+
+var ANIMATION_SPEEDS = []string{"slow", "normal", "fast", "instant"}
+var ANIMATION_SPEED_FACTORS = map[string]float64{"slow": 0.66, "normal": 1, "fast": 2}
+
+// OPPONENTS are the agents a computer seat can be played by, weakest first.
+var OPPONENTS = []string{"random", "sample", "model"}
+
+// LANGUAGES the game can be shown in. Only English has been written so far.
+var LANGUAGES = []string{"English"}
+
+// Settings are the player's preferences, kept between sessions.
+type Settings struct {
+	MasterVolume   int    `json:"master_volume"` // percent
+	SFXVolume      int    `json:"sfx_volume"`    // percent of the master volume
+	AnimationSpeed string `json:"animation_speed"`
+	Opponent       string `json:"opponent"` // the agent for computer seats
+	Colorblind     bool   `json:"colorblind"`
+	Fullscreen     bool   `json:"fullscreen"`
+	Language       string `json:"language"`
+}
+
+func DefaultSettings() *Settings {
+	return &Settings{
+		MasterVolume:   100,
+		SFXVolume:      100,
+		AnimationSpeed: "normal",
+		Opponent:       "sample",
+		Language:       "English",
+	}
+}
+
+var CurrentSettings = DefaultSettings()
+
+// LoadSettings reads the saved settings and applies them. Anything missing
+// or unknown keeps its default.
+func LoadSettings() error {
+	s := DefaultSettings()
+	err := SETTINGS_KEY.Load(s)
+	if indexOf(ANIMATION_SPEEDS, s.AnimationSpeed) == -1 {
+		s.AnimationSpeed = "normal"
+	}
+	if indexOf(OPPONENTS, s.Opponent) == -1 {
+		s.Opponent = "sample"
+	}
+	if indexOf(LANGUAGES, s.Language) == -1 {
+		s.Language = "English"
+	}
+	s.MasterVolume = min(max(s.MasterVolume, 0), 100)
+	s.SFXVolume = min(max(s.SFXVolume, 0), 100)
+	CurrentSettings = s
+	s.Apply()
+	return err
+}
+
+func (s *Settings) Save() error {
+	return SETTINGS_KEY.Save(s)
+}
+
+// Apply puts the settings that aren't read as they are needed into effect.
+func (s *Settings) Apply() {
+	ebiten.SetFullscreen(s.Fullscreen)
+	if s.Colorblind {
+		HintColor = color.RGBA{0x56, 0xb4, 0xe9, 0xff}
+		BlunderColor = color.RGBA{0xe6, 0x9f, 0x00, 0xff}
+	} else {
+		HintColor = color.RGBA{0xff, 0xe0, 0x66, 0xff}
+		BlunderColor = color.RGBA{0xff, 0x70, 0x60, 0xff}
+	}
+}
+
+// Frames scales an animation of n frames by the animation speed. Instant
+// animations still take two frames, the fewest an animator can have.
+func Frames(n int) int {
+	factor, ok := ANIMATION_SPEED_FACTORS[CurrentSettings.AnimationSpeed]
+	if !ok {
+		return 2
+	}
+	return max(int(float64(n)/factor), 2)
+}
+
+// PlaySound plays a sound effect at the volume set by the player.
+func PlaySound(audioContext *audio.Context, sound []byte) {
+	player := audioContext.NewPlayerFromBytes(sound)
+	player.SetVolume(float64(CurrentSettings.MasterVolume*CurrentSettings.SFXVolume) / 10000)
+	player.Play()
+}
+
+// HoverTile outlines the slot a card would go in, in white rather than green
+// for colorblind players.
+func HoverTile() *ebiten.Image {
+	if CurrentSettings.Colorblind {
+		return res.GetImage("hexoutline")
+	}
+	return res.GetImage("hexoutlinegreen")
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// cycle steps d places through list from s, wrapping around.
+func cycle(list []string, s string, d int) string {
+	i := indexOf(list, s)
+	return list[((i+d)%len(list)+len(list))%len(list)]
+}
+
+func percent(v int) string {
+	return strconv.Itoa(v) + "%"
+}
+
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+const SETTINGS_Y = 200
+const SETTINGS_STEP = 55
+const SETTINGS_BACK_Y = 640
+
+type SettingsRow struct {
+	Text   func() string
+	Change func(d int)
+}
+
+// SettingsScene changes the settings, saving each change as it is made. It
+// goes back to the scene it was opened from.
+type SettingsScene struct {
+	BaseScene
+	Return string
+	Rows   []SettingsRow
+}
+
+func NewSettingsScene(returnTo string) *SettingsScene {
+	s := CurrentSettings
+	volume := func(v *int) func(d int) {
+		return func(d int) { *v = min(max(*v+d*10, 0), 100) }
+	}
+	return &SettingsScene{
+		Return: returnTo,
+		Rows: []SettingsRow{
+			{func() string { return "Master volume: " + percent(s.MasterVolume) }, volume(&s.MasterVolume)},
+			{func() string { return "Sound effects: " + percent(s.SFXVolume) }, volume(&s.SFXVolume)},
+			{func() string { return "Animation speed: " + capitalize(s.AnimationSpeed) },
+				func(d int) { s.AnimationSpeed = cycle(ANIMATION_SPEEDS, s.AnimationSpeed, d) }},
+			{func() string { return "Computer opponent: " + s.Opponent },
+				func(d int) { s.Opponent = cycle(OPPONENTS, s.Opponent, d) }},
+			{func() string { return "Colorblind mode: " + onOff(s.Colorblind) }, func(int) { s.Colorblind = !s.Colorblind }},
+			{func() string { return "Fullscreen: " + onOff(s.Fullscreen) }, func(int) { s.Fullscreen = !s.Fullscreen }},
+			{func() string { return "Language: " + s.Language },
+				func(d int) { s.Language = cycle(LANGUAGES, s.Language, d) }},
+		},
+	}
+}
+
+func (s *SettingsScene) Update() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		s.SceneManager.SwitchToScene(s.Return)
+		return
+	}
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	cx, cy := ui.AdjustedCursorPosition()
+	if util.XYinRect(cx, cy, 640-120, SETTINGS_BACK_Y-20, 240, 40) {
+		s.SceneManager.SwitchToScene(s.Return)
+		return
+	}
+	for i, row := range s.Rows {
+		y := float64(SETTINGS_Y + i*SETTINGS_STEP)
+		d := 0
+		if util.XYinRect(cx, cy, 640-300, y-20, 60, 40) {
+			d = -1
+		} else if util.XYinRect(cx, cy, 640-240, y-20, 540, 40) {
+			d = 1
+		}
+		if d != 0 {
+			row.Change(d)
+			CurrentSettings.Apply()
+			if err := CurrentSettings.Save(); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+func (s *SettingsScene) Draw(screen *ui.ScaledScreen) {
+	screen.Screen.Fill(color.RGBA{0x44, 0x5c, 0x47, 0xff})
+	screen.DrawTextCenteredAt("Settings", 56, 640, 100, color.White)
+	for i, row := range s.Rows {
+		y := float64(SETTINGS_Y + i*SETTINGS_STEP)
+		screen.DrawTextCenteredAt("<", 32, 640-270, y, color.White)
+		screen.DrawTextCenteredAt(row.Text(), 32, 640, y, color.White)
+		screen.DrawTextCenteredAt(">", 32, 640+270, y, color.White)
+	}
+	screen.DrawUnfilledRect(640-120, SETTINGS_BACK_Y-20, 240, 40, 2, color.White)
+	screen.DrawTextCenteredAt("Back", 32, 640, SETTINGS_BACK_Y, color.White)
+}
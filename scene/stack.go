@@ -0,0 +1,52 @@
+package scene
+
+import (
+	"image/color"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+const STACK_SCALE = 0.4
+const STACK_STEP = 52
+const STACK_PER_ROW = 7
+const STACK_ROW_H = 62
+
+var StackBackground = color.RGBA{0x22, 0x2e, 0x24, 0xe0}
+
+// DrawStack lays out every card of the revealed stack, the open card first
+// and the longest buried last, below the deck and the stack.
+func (g *GameScene) DrawStack(screen *ui.ScaledScreen) {
+	discards := g.Game.Discards
+	rows := max(1, (len(discards)+STACK_PER_ROW-1)/STACK_PER_ROW)
+	w := float64(STACK_PER_ROW*STACK_STEP + 12)
+	cx := (g.Layout.DeckX + g.Layout.DiscardX + ui.TILE_SIZE_X) / 2
+	x, y := cx-w/2, g.Layout.DiscardY+175
+	screen.DrawRect(x, y, w, float64(rows*STACK_ROW_H+34), StackBackground)
+	title := "Revealed stack, " + strconv.Itoa(len(discards)) + " cards, top first"
+	if len(discards) == 1 {
+		title = "Revealed stack, 1 card"
+	}
+	screen.DrawTextCenteredAt(title, 18, cx, y+14, color.White)
+	for i := range discards {
+		c := discards[len(discards)-1-i]
+		opts := &ebiten.DrawImageOptions{}
+		opts.GeoM.Scale(STACK_SCALE, STACK_SCALE)
+		opts.GeoM.Translate(x+8+float64(i%STACK_PER_ROW)*STACK_STEP, y+30+float64(i/STACK_PER_ROW)*STACK_ROW_H)
+		screen.DrawImage(g.Tiles.TileAtIJ(c.Value-1, c.Color*4), opts)
+	}
+}
+
+// StackShown reports whether the stack view is up: pinned open with S, or
+// while the cursor is over the revealed stack or the tracker's stack count.
+func (g *GameScene) StackShown(cx, cy float64) bool {
+	if len(g.Game.Discards) == 0 || g.DragSprite != nil || g.UIState == GAME_OVER || g.UIState == PUZZLE_DONE {
+		return false
+	}
+	if g.ShowStack || util.XYinRect(cx, cy, g.Layout.DiscardX, g.Layout.DiscardY, ui.TILE_SIZE_X, ui.TILE_SIZE_Y) {
+		return true
+	}
+	return g.ShowTracker && util.XYinRect(cx, cy, TRACKER_STACK_X, TRACKER_Y, 100, 22)
+}
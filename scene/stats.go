@@ -0,0 +1,218 @@
+package scene
+
+import (
+	"image/color"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+const STATS_TABLE_Y = 190
+const STATS_ROW_H = 34
+const STATS_LOWER_Y = 440
+const STATS_RECENT_X = 680
+const STATS_RECENT_LINES = 8
+const STATS_LINE_H = 27
+
+var STATS_COLUMNS = []float64{230, 430, 530, 630, 730, 860, 980}
+var STATS_HEADERS = []string{"Opponent", "Games", "Won", "Drawn", "Lost", "Average", "Best"}
+
+var OPPONENT_LABELS = map[string]string{
+	"random":            "Computer (random)",
+	"sample":            "Computer (sample)",
+	"model":             "Computer (model)",
+	core.HUMAN_OPPONENT: "Human",
+	core.SOLO_OPPONENT:  "Solo",
+}
+
+// opponentName is the agent in the other seat, or human if nobody is.
+func (g *GameScene) opponentName(seat int) string {
+	if name := g.AgentNames[1-seat]; name != "" {
+		return name
+	}
+	return core.HUMAN_OPPONENT
+}
+
+// SeatName is the name of the player in seat, or the kind of computer player.
+func (g *GameScene) SeatName(seat int) string {
+	if name := g.AgentNames[seat]; name != "" {
+		return OPPONENT_LABELS[name]
+	}
+	return g.PlayerNames[seat]
+}
+
+func (g *GameScene) seatNames() [2]string {
+	return [2]string{g.SeatName(0), g.SeatName(1)}
+}
+
+// SaveMatchResults adds the finished game to the profile of each human
+// player.
+func (g *GameScene) SaveMatchResults() {
+	profiles, err := core.LoadProfiles()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	now := time.Now()
+	for seat := range 2 {
+		if g.Agents[seat] != nil || (seat == 1 && g.Game.Solo) {
+			continue
+		}
+		m := core.NewMatchResult(g.Game, seat, g.opponentName(seat), now)
+		profiles.Get(g.PlayerNames[seat]).Add(m)
+	}
+	if err := profiles.Save(); err != nil {
+		log.Println(err)
+	}
+}
+
+// StatsScene shows the results of one profile at a time, by opponent, with
+// the edges they score most and their recent matches. A recent match can be
+// clicked to watch it again.
+type StatsScene struct {
+	BaseScene
+	Profiles *core.Profiles
+	Index    int
+	Scroll   int // recent matches scrolled back by
+}
+
+func NewStatsScene() *StatsScene {
+	profiles, err := core.LoadProfiles()
+	if err != nil {
+		log.Println(err)
+	}
+	return &StatsScene{Profiles: profiles}
+}
+
+func (s *StatsScene) profile() *core.Profile {
+	if len(s.Profiles.Players) == 0 {
+		return nil
+	}
+	return s.Profiles.Players[s.Index]
+}
+
+// recentRange is the part of the recent matches on show, newest first.
+func (s *StatsScene) recentRange(p *core.Profile) (int, int) {
+	end := len(p.Recent) - s.Scroll
+	return max(0, end-STATS_RECENT_LINES), end
+}
+
+func (s *StatsScene) Update() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		s.SceneManager.SwitchToScene("menu")
+		return
+	}
+	p := s.profile()
+	cx, cy := ui.AdjustedCursorPosition()
+	if p != nil && util.XYinRect(cx, cy, STATS_RECENT_X, STATS_LOWER_Y, 520, (STATS_RECENT_LINES+1)*STATS_LINE_H) {
+		_, dy := ebiten.Wheel()
+		if dy > 0 {
+			s.Scroll += 1
+		} else if dy < 0 {
+			s.Scroll -= 1
+		}
+		s.Scroll = max(0, min(s.Scroll, len(p.Recent)-STATS_RECENT_LINES))
+	}
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	if util.XYinRect(cx, cy, RULES_X-10, RULES_Y-10, 140, 35) {
+		s.SceneManager.SwitchToScene("menu")
+		return
+	}
+	if p == nil {
+		return
+	}
+	n := len(s.Profiles.Players)
+	if util.XYinRect(cx, cy, 640-330, 120-20, 60, 40) {
+		s.Index, s.Scroll = (s.Index+n-1)%n, 0
+	} else if util.XYinRect(cx, cy, 640+270, 120-20, 60, 40) {
+		s.Index, s.Scroll = (s.Index+1)%n, 0
+	}
+	start, end := s.recentRange(p)
+	for i := start; i < end; i++ {
+		y := STATS_LOWER_Y + float64(end-i)*STATS_LINE_H
+		if util.XYinRect(cx, cy, STATS_RECENT_X, y-STATS_LINE_H/2, 520, STATS_LINE_H) {
+			s.Replay(p.Recent[i])
+			return
+		}
+	}
+}
+
+func (s *StatsScene) Replay(m core.MatchResult) {
+	r, err := core.ParseShareCode(m.Code)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	s.SceneManager.AddScene("replay", NewReplayScene(r))
+	s.SceneManager.SwitchToScene("replay")
+}
+
+func statsRow(screen *ui.ScaledScreen, y float64, label string, r *core.Record, clr color.Color) {
+	average := strconv.FormatFloat(r.AverageScore(), 'f', 1, 64)
+	cells := []string{label, strconv.Itoa(r.Games), strconv.Itoa(r.Wins), strconv.Itoa(r.Draws),
+		strconv.Itoa(r.Losses), average, strconv.Itoa(r.BestScore)}
+	for i, text := range cells {
+		screen.DrawTextCenteredAt(text, 24, STATS_COLUMNS[i], y, clr)
+	}
+}
+
+func (s *StatsScene) Draw(screen *ui.ScaledScreen) {
+	screen.Screen.Fill(color.RGBA{0x44, 0x5c, 0x47, 0xff})
+	screen.DrawText("Back to menu", 18, RULES_X, RULES_Y, color.White)
+	screen.DrawTextCenteredAt("Statistics", 56, 640, 50, color.White)
+
+	p := s.profile()
+	if p == nil {
+		screen.DrawTextCenteredAt("Finish a game to start keeping statistics.", 32, 640, 300, color.White)
+		return
+	}
+	screen.DrawTextCenteredAt("<", 32, 640-300, 120, color.White)
+	screen.DrawTextCenteredAt(p.Name, 32, 640, 120, color.White)
+	screen.DrawTextCenteredAt(">", 32, 640+300, 120, color.White)
+
+	for i, h := range STATS_HEADERS {
+		screen.DrawTextCenteredAt(h, 24, STATS_COLUMNS[i], STATS_TABLE_Y-STATS_ROW_H, HintColor)
+	}
+	opponents := p.Opponents()
+	for i, o := range opponents {
+		statsRow(screen, STATS_TABLE_Y+float64(i*STATS_ROW_H), OPPONENT_LABELS[o], p.Records[o], color.White)
+	}
+	total := p.Total()
+	if total.Games > 0 {
+		statsRow(screen, STATS_TABLE_Y+float64(len(opponents)*STATS_ROW_H), "All opponents", total, HintColor)
+	}
+
+	// solo games count towards the edges, since every edge is built the same
+	edges := &core.Record{}
+	for _, r := range p.Records {
+		edges.Merge(r)
+	}
+	screen.DrawText("Most often scoring edges", 24, 80, STATS_LOWER_Y-12, HintColor)
+	for i, e := range edges.TopEdges() {
+		text := capitalize(EDGE_NAMES[e]) + ": " + strconv.Itoa(edges.EdgeScored[e]) + " of " + strconv.Itoa(edges.Games)
+		screen.DrawText(text, 20, 80, STATS_LOWER_Y+(i+1)*STATS_LINE_H-10, color.White)
+	}
+
+	screen.DrawText("Recent matches, click to replay", 24, STATS_RECENT_X, STATS_LOWER_Y-12, HintColor)
+	cx, cy := ui.AdjustedCursorPosition()
+	start, end := s.recentRange(p)
+	for i := end - 1; i >= start; i-- {
+		m := p.Recent[i]
+		y := STATS_LOWER_Y + float64(end-i)*STATS_LINE_H
+		clr := color.Color(color.White)
+		if util.XYinRect(cx, cy, STATS_RECENT_X, y-STATS_LINE_H/2, 520, STATS_LINE_H) {
+			clr = HintColor
+		}
+		text := m.Date + "   " + OPPONENT_LABELS[m.Opponent] + "   " + strconv.Itoa(m.Score) + " - " +
+			strconv.Itoa(m.OppScore) + "   " + m.Outcome
+		screen.DrawText(text, 20, STATS_RECENT_X, int(y)-10, clr)
+	}
+}
@@ -0,0 +1,73 @@
+package scene
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/ui"
+	"github.com/prizelobby/pyramid-rummy/util"
+)
+
+const TRACKER_X = 30
+const TRACKER_Y = 20
+const TRACKER_STEP = 30
+const TRACKER_TOGGLE_Y = EDGES_Y + 35
+const TRACKER_STACK_X = TRACKER_X + 170
+
+// CardTextColors are lighter versions of the card colors that read well on
+// the table.
+var CardTextColors = [2]color.RGBA{
+	{0xc8, 0xa8, 0xf0, 0xff},
+	{0xf0, 0xc8, 0x60, 0xff},
+}
+
+func formatChance(p float64) string {
+	return strconv.Itoa(int(math.Round(p*100))) + "%"
+}
+
+// DrawTracker lists how many copies of each card are still unseen and the
+// chances for the next card drawn. Only cards every player has seen count,
+// so the panel never gives the deck order away. Hovering the stack count
+// shows the cards out of play in the stack.
+func (g *GameScene) DrawTracker(screen *ui.ScaledScreen) {
+	s := core.StateFromGame(g.Game)
+	screen.DrawText("Unseen cards", 18, TRACKER_X, TRACKER_Y, color.White)
+	screen.DrawText("Stack "+strconv.Itoa(len(g.Game.Discards)), 18, TRACKER_STACK_X, TRACKER_Y, color.White)
+	for t, u := range s.Unseen {
+		c := core.TypeToCard(t)
+		x := TRACKER_X + 12 + float64(c.Value-1)*TRACKER_STEP
+		y := TRACKER_Y + 40 + float64(c.Color)*42
+		text := CardTextColors[c.Color]
+		if u == 0 {
+			text.A = 0x50
+		}
+		screen.DrawTextCenteredAt(strconv.Itoa(c.Value), 22, x, y, text)
+		screen.DrawTextCenteredAt("x"+strconv.Itoa(u), 14, x, y+18, text)
+	}
+
+	x := TRACKER_X + 10*TRACKER_STEP + 15
+	screen.DrawText("Deck "+strconv.Itoa(s.UnseenCount()), 18, x, TRACKER_Y, color.White)
+	screen.DrawText("Purple "+formatChance(s.ColorChance(0)), 18, x, TRACKER_Y+30, CardTextColors[0])
+	screen.DrawText("Yellow "+formatChance(s.ColorChance(1)), 18, x, TRACKER_Y+55, CardTextColors[1])
+	screen.DrawText("- +", 18, x, TRACKER_Y+80, color.White)
+	atLeast := strconv.Itoa(g.TrackerValue) + "+ " + formatChance(s.AtLeastChance(g.TrackerValue))
+	screen.DrawText(atLeast, 18, x+35, TRACKER_Y+80, color.White)
+}
+
+// UpdateTracker lets the player change the value the tracker gives the
+// chance of drawing at least.
+func (g *GameScene) UpdateTracker(cx, cy float64) {
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	x, y := float64(TRACKER_X+10*TRACKER_STEP+15), float64(TRACKER_Y+80)
+	if util.XYinRect(cx, cy, x-5, y-5, 15, 30) && g.TrackerValue > 1 {
+		g.TrackerValue -= 1
+	} else if util.XYinRect(cx, cy, x+12, y-5, 15, 30) && g.TrackerValue < 10 {
+		g.TrackerValue += 1
+	}
+}
@@ -0,0 +1,214 @@
+package scene
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/prizelobby/pyramid-rummy/core"
+	"github.com/prizelobby/pyramid-rummy/ui"
+)
+
+// TUTORIAL_POSITION deals the tutorial deck, so every card the player reveals
+// is the one the steps talk about.
+const TUTORIAL_POSITION = "----------/---------- - 5p2p7y3y1p9p6p8p4yTy2y3p 1 2"
+
+// EDGE_NAMES describe the edges of core.Edges as they look on screen.
+var EDGE_NAMES = [6]string{
+	"upper left edge",
+	"upper right edge",
+	"bottom edge",
+	"edge from the top corner to the peak",
+	"edge from the left corner to the peak",
+	"edge from the right corner to the peak",
+}
+
+var COLOR_NAMES = [2]string{"purple", "yellow"}
+
+type TutorialStep struct {
+	Text   string
+	Action core.AgentEvent
+}
+
+func tutorialDraw(text string) TutorialStep {
+	return TutorialStep{Text: text, Action: core.AgentEvent{EventType: core.DRAW_CARDS}}
+}
+
+func tutorialPlay(slot int, text string) TutorialStep {
+	return TutorialStep{Text: text, Action: core.AgentEvent{EventType: core.PLAY_CARD, Target: slot}}
+}
+
+var TutorialSteps = []TutorialStep{
+	tutorialDraw("You build a pyramid of ten cards, one card each turn. Click the deck to reveal a card."),
+	tutorialPlay(3, "The revealed card is open for you to take. Drag the 5p to the bottom left corner."),
+	tutorialDraw("Placing a card ends your turn. Reveal the next card."),
+	tutorialPlay(1, "Put the 2p between the top and bottom left corners."),
+	tutorialDraw("Reveal another card."),
+	tutorialPlay(0, "Put the 7y in the top corner. That finishes the first edge."),
+	tutorialDraw("Reveal the next card."),
+	tutorialPlay(2, "Put the 3y on the upper right edge."),
+	tutorialDraw("Reveal the next card."),
+	tutorialDraw("A 1 is worth very little. You can reveal a second card each turn, so reveal another."),
+	tutorialPlay(5, "After two reveals you have to place. Put the 9p in the bottom right corner."),
+	tutorialPlay(4, "The 1p you passed on is open again. You can take it instead of revealing. Put it in the middle of the bottom edge."),
+	tutorialDraw("The bottom layer is full. Reveal a card for the next layer."),
+	tutorialPlay(6, "Cards above rest on the three below them. Put the 6p above the top corner."),
+	tutorialDraw("Reveal the next card."),
+	tutorialPlay(7, "Put the 8p above the bottom left corner."),
+	tutorialDraw("Reveal the next card."),
+	tutorialPlay(8, "Put the 4y above the bottom right corner."),
+	tutorialDraw("One card to go. Reveal it."),
+	tutorialPlay(9, "Finish with the Ty at the peak. It sits on three edges at once."),
+}
+
+type Tutorial struct {
+	Steps       []TutorialStep
+	Step        int
+	Explanation string // how the last placement scored
+	Edges       []int  // edges finished by the last placement
+	Reminder    string // shown after a move the step does not ask for
+}
+
+func (t *Tutorial) Current() *TutorialStep {
+	if t.Step >= len(t.Steps) {
+		return nil
+	}
+	return &t.Steps[t.Step]
+}
+
+// NewTutorialGameScene starts a solo game on the tutorial deck that only
+// accepts the move each step asks for.
+func NewTutorialGameScene(audioContext *audio.Context) *GameScene {
+	g := NewGameScene(audioContext)
+	game, err := core.NewGameFromPosition(TUTORIAL_POSITION, 0)
+	if err != nil {
+		panic(err)
+	}
+	game.Solo = true
+	g.Game = game
+	g.Agents = [2]core.GameAgent{}
+	g.Layout = SoloLayout
+	g.Tutorial = &Tutorial{Steps: TutorialSteps}
+	// keep the board plain, the steps point out what matters
+	g.ShowEdges = false
+	g.ShowTracker = false
+	return g
+}
+
+// Allowed reports whether the player may make move e, leaving a reminder for
+// the tutorial if not. Outside the tutorial every legal move is allowed.
+func (g *GameScene) Allowed(e core.AgentEvent) bool {
+	if g.Tutorial == nil {
+		return true
+	}
+	step := g.Tutorial.Current()
+	if step != nil && step.Action == e {
+		g.Tutorial.Reminder = ""
+		return true
+	}
+	g.Tutorial.Reminder = "Not that one, follow the highlight."
+	return false
+}
+
+// DescribeEdge explains how edge i of p scores.
+func DescribeEdge(p *core.Pyramid, i int) string {
+	d := core.Edges[i]
+	cards := p.Cards[d[0]].String() + ", " + p.Cards[d[1]].String() + " and " + p.Cards[d[2]].String()
+	text := "The " + EDGE_NAMES[i] + " is " + cards + ". "
+	score, slot := p.EdgeScore(i)
+	if slot == -1 {
+		return text + "They are all " + COLOR_NAMES[p.Cards[d[0]].Color] + ", so it scores 0."
+	}
+	c := p.Cards[slot]
+	return text + "The " + c.String() + " is the only " + COLOR_NAMES[c.Color] + " card, so the edge scores its value, " + strconv.Itoa(score) + "."
+}
+
+// AdvanceTutorial moves on to the next step after the player has made move e,
+// explaining any edges the move finished.
+func (g *GameScene) AdvanceTutorial(e core.AgentEvent) {
+	t := g.Tutorial
+	t.Step += 1
+	t.Explanation = ""
+	t.Edges = nil
+	if e.EventType != core.PLAY_CARD {
+		return
+	}
+	p := g.Game.Pyramid1
+	for i, edge := range core.Edges {
+		if (edge[0] == e.Target || edge[1] == e.Target || edge[2] == e.Target) && p.EdgeComplete(i) {
+			t.Edges = append(t.Edges, i)
+			t.Explanation += DescribeEdge(p, i) + "\n"
+		}
+	}
+	if t.Explanation != "" {
+		t.Explanation += "Your score is now " + strconv.Itoa(p.Score()) + ", the sum of your edges.\n\n"
+	}
+}
+
+// wrapText breaks s into lines of at most width characters.
+func wrapText(s string, width int) string {
+	var sb strings.Builder
+	for i, paragraph := range strings.Split(s, "\n") {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		line := 0
+		for _, word := range strings.Fields(paragraph) {
+			if line > 0 && line+1+len(word) > width {
+				sb.WriteString("\n")
+				line = 0
+			} else if line > 0 {
+				sb.WriteString(" ")
+				line += 1
+			}
+			sb.WriteString(word)
+			line += len(word)
+		}
+	}
+	return sb.String()
+}
+
+const TUTORIAL_TEXT_X = 1070
+const TUTORIAL_TEXT_Y = 380
+const TUTORIAL_TEXT_WIDTH = 30
+
+// DrawTutorial shows the current step beside the pyramid and highlights the
+// deck or the slot it asks for, along with the edges the last card finished.
+func (g *GameScene) DrawTutorial(screen *ui.ScaledScreen) {
+	t := g.Tutorial
+	text := t.Explanation
+	if step := t.Current(); step != nil {
+		text += step.Text
+	} else {
+		text += "That's the whole game: the highest pyramid after ten turns each wins. Return to the menu to play the computer."
+	}
+	screen.DrawTextCenteredAt(wrapText(text, TUTORIAL_TEXT_WIDTH), 20, TUTORIAL_TEXT_X, TUTORIAL_TEXT_Y, color.White)
+	if t.Reminder != "" {
+		screen.DrawTextCenteredAt(t.Reminder, 20, TUTORIAL_TEXT_X, 660, HintColor)
+	}
+
+	for _, i := range t.Edges {
+		for _, slot := range core.Edges[i] {
+			x := g.Layout.XLocs[0][slot] + ui.TILE_SIZE_X/2
+			y := g.Layout.YLocs[0][slot] - ui.TILE_HEIGHT + (ui.TILE_SIZE_Y-ui.TILE_HEIGHT)/2
+			screen.DrawCircle(x, y, 8, HintColor)
+		}
+	}
+
+	step := t.Current()
+	if step == nil || g.UIState != WAITING_FOR_PLAYER_MOVE {
+		return
+	}
+	if step.Action.EventType == core.DRAW_CARDS {
+		screen.DrawUnfilledRect(g.Layout.DeckX-4, g.Layout.DeckY-4, DECK_BUTTON_W+8, DECK_BUTTON_H+8, 3, HintColor)
+		return
+	}
+	if g.DragSprite == nil {
+		screen.DrawUnfilledRect(g.Layout.DiscardX-4, g.Layout.DiscardY-4, ui.TILE_SIZE_X+8, ui.TILE_SIZE_Y+8, 3, HintColor)
+	}
+	opt := &ebiten.DrawImageOptions{}
+	opt.GeoM.Translate(g.Layout.XLocs[0][step.Action.Target], g.Layout.YLocs[0][step.Action.Target])
+	screen.DrawImage(g.HoverTile, opt)
+}
@@ -0,0 +1,68 @@
+//go:build !js
+
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Files keeps each value in a JSON file under Dir, with the parts of the key
+// as folders.
+type Files struct {
+	Dir string
+}
+
+// NewFiles keeps values in the game's folder in the user's config directory.
+func NewFiles() (*Files, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Files{Dir: filepath.Join(dir, APP_NAME)}, nil
+}
+
+func defaultBackend() Backend {
+	f, err := NewFiles()
+	if err != nil {
+		log.Println("can't save player data: " + err.Error())
+		return NewMemory()
+	}
+	return f
+}
+
+func (f *Files) path(key string) string {
+	return filepath.Join(f.Dir, filepath.FromSlash(key)+".json")
+}
+
+func (f *Files) Read(key string) ([]byte, error) {
+	b, err := os.ReadFile(f.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (f *Files) Write(key string, b []byte) error {
+	p := f.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first so a crash can't leave half a file
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (f *Files) Delete(key string) error {
+	err := os.Remove(f.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
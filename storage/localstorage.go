@@ -0,0 +1,77 @@
+//go:build js
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"syscall/js"
+)
+
+// LocalStorage keeps values in the browser's localStorage, with keys prefixed
+// by the app name.
+type LocalStorage struct {
+	ls js.Value
+}
+
+// catch runs f and returns the JavaScript exception it throws as an error.
+// The browser throws when storage is blocked or full, which would otherwise
+// crash the game.
+func catch(f func() js.Value) (v js.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	return f(), nil
+}
+
+func NewLocalStorage() (*LocalStorage, error) {
+	// read it through Reflect.get, since a plain Get can't catch what the
+	// browser throws when storage is blocked
+	ls, err := catch(func() js.Value {
+		return js.Global().Get("Reflect").Call("get", js.Global(), "localStorage")
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ls.IsUndefined() || ls.IsNull() {
+		return nil, errors.New("localStorage is not available")
+	}
+	return &LocalStorage{ls: ls}, nil
+}
+
+func defaultBackend() Backend {
+	l, err := NewLocalStorage()
+	if err != nil {
+		log.Println("can't save player data: " + err.Error())
+		return NewMemory()
+	}
+	return l
+}
+
+func (l *LocalStorage) Read(key string) ([]byte, error) {
+	v, err := catch(func() js.Value { return l.ls.Call("getItem", APP_NAME+"/"+key) })
+	if err != nil {
+		return nil, err
+	}
+	if v.IsNull() {
+		return nil, nil
+	}
+	return []byte(v.String()), nil
+}
+
+func (l *LocalStorage) Write(key string, b []byte) error {
+	_, err := catch(func() js.Value { return l.ls.Call("setItem", APP_NAME+"/"+key, string(b)) })
+	return err
+}
+
+func (l *LocalStorage) Delete(key string) error {
+	_, err := catch(func() js.Value { return l.ls.Call("removeItem", APP_NAME+"/"+key) })
+	return err
+}
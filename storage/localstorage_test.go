@@ -0,0 +1,46 @@
+//go:build js
+
+package storage
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestLocalStorageBlocked(t *testing.T) {
+	js.Global().Get("Object").Call("defineProperty", js.Global(), "localStorage", map[string]any{
+		"configurable": true,
+		"get":          js.Global().Get("Function").New(`throw new DOMException("blocked", "SecurityError")`),
+	})
+	if _, err := NewLocalStorage(); err == nil {
+		t.Error("blocked localStorage gave no error")
+	}
+	if _, ok := defaultBackend().(*Memory); !ok {
+		t.Error("blocked localStorage didn't fall back to memory")
+	}
+}
+
+func TestLocalStorageFull(t *testing.T) {
+	full := js.Global().Get("Function").New(`throw new DOMException("full", "QuotaExceededError")`)
+	js.Global().Get("Object").Call("defineProperty", js.Global(), "localStorage", map[string]any{
+		"configurable": true,
+		"value": map[string]any{
+			"getItem":    full,
+			"setItem":    full,
+			"removeItem": full,
+		},
+	})
+	l, err := NewLocalStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Write("test/value", []byte("{}")); err == nil {
+		t.Error("writing to full storage gave no error")
+	}
+	if _, err := l.Read("test/value"); err == nil {
+		t.Error("reading from failing storage gave no error")
+	}
+	if err := l.Delete("test/value"); err == nil {
+		t.Error("deleting from failing storage gave no error")
+	}
+}
@@ -0,0 +1,33 @@
+package storage
+
+import "sync"
+
+// Memory keeps values for as long as the process runs.
+type Memory struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{values: map[string][]byte{}}
+}
+
+func (m *Memory) Read(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key], nil
+}
+
+func (m *Memory) Write(key string, b []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = append([]byte(nil), b...)
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
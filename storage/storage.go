@@ -0,0 +1,91 @@
+// Package storage saves small pieces of player data, such as the daily
+// challenge history, as JSON. The desktop build keeps them in files under the
+// user's config directory and the web build keeps them in localStorage. Both
+// fall back to keeping them in memory for the session if that doesn't work.
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const APP_NAME = "pyramid-rummy"
+
+// Backend holds saved values by key. Keys are made of names separated by
+// slashes.
+type Backend interface {
+	// Read returns nil with no error if nothing is saved under key.
+	Read(key string) ([]byte, error)
+	Write(key string, b []byte) error
+	// Delete does nothing if nothing is saved under key.
+	Delete(key string) error
+}
+
+var backend = defaultBackend()
+
+// Use makes b the backend for every key, for example a Memory so nothing
+// outside the process is touched.
+func Use(b Backend) {
+	backend = b
+}
+
+// Key is where a kind of value is saved and which version of its layout the
+// code reads and writes. Values are saved with their version, and Migrate is
+// given anything saved at an older version to bring it up to date. Without
+// Migrate older values are read as they are.
+type Key struct {
+	Namespace string
+	Name      string
+	Version   int
+	Migrate   func(version int, data json.RawMessage) (json.RawMessage, error)
+}
+
+func (k Key) String() string {
+	return k.Namespace + "/" + k.Name
+}
+
+type record struct {
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Load reads the value saved under k into v. If nothing has been saved v is
+// left unchanged and no error is returned.
+func (k Key) Load(v any) error {
+	b, err := backend.Read(k.String())
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return nil
+	}
+	r := record{}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return fmt.Errorf("%s: %w", k, err)
+	}
+	if r.Version > k.Version {
+		return fmt.Errorf("%s was saved by a newer version of the game", k)
+	}
+	if r.Version < k.Version && k.Migrate != nil {
+		if r.Data, err = k.Migrate(r.Version, r.Data); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return json.Unmarshal(r.Data, v)
+}
+
+func (k Key) Save(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(record{Version: k.Version, Data: data})
+	if err != nil {
+		return err
+	}
+	return backend.Write(k.String(), b)
+}
+
+func (k Key) Delete() error {
+	return backend.Delete(k.String())
+}
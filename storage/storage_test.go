@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testValue struct {
+	Count int `json:"count"`
+}
+
+func TestKeyLoadSaveDelete(t *testing.T) {
+	Use(NewMemory())
+	k := Key{Namespace: "test", Name: "value", Version: 1}
+
+	v := testValue{Count: 7}
+	if err := k.Load(&v); err != nil || v.Count != 7 {
+		t.Fatalf("loading an unsaved key gave %v, %v", v, err)
+	}
+	if err := k.Save(testValue{Count: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Load(&v); err != nil || v.Count != 3 {
+		t.Fatalf("loaded %v, %v after saving 3", v, err)
+	}
+	if err := k.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	v = testValue{}
+	if err := k.Load(&v); err != nil || v.Count != 0 {
+		t.Fatalf("loaded %v, %v after deleting", v, err)
+	}
+}
+
+func TestKeyNewerVersion(t *testing.T) {
+	Use(NewMemory())
+	newer := Key{Namespace: "test", Name: "value", Version: 2}
+	if err := newer.Save(testValue{Count: 1}); err != nil {
+		t.Fatal(err)
+	}
+	k := Key{Namespace: "test", Name: "value", Version: 1}
+	err := k.Load(&testValue{})
+	if err == nil || !strings.Contains(err.Error(), "newer version") {
+		t.Errorf("loading a value from a newer version gave %v", err)
+	}
+}
+
+func TestKeyMigrate(t *testing.T) {
+	Use(NewMemory())
+	old := Key{Namespace: "test", Name: "value", Version: 1}
+	if err := old.Save(map[string]int{"n": 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated := -1
+	k := Key{Namespace: "test", Name: "value", Version: 2,
+		Migrate: func(version int, data json.RawMessage) (json.RawMessage, error) {
+			migrated = version
+			var o map[string]int
+			if err := json.Unmarshal(data, &o); err != nil {
+				return nil, err
+			}
+			return json.Marshal(testValue{Count: o["n"]})
+		}}
+	v := testValue{}
+	if err := k.Load(&v); err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 1 || v.Count != 4 {
+		t.Errorf("migrated from version %d to %v", migrated, v)
+	}
+
+	// values already at the current version are not migrated again
+	if err := k.Save(v); err != nil {
+		t.Fatal(err)
+	}
+	migrated = -1
+	if err := k.Load(&v); err != nil || migrated != -1 || v.Count != 4 {
+		t.Errorf("loading a current value migrated from %d to %v, %v", migrated, v, err)
+	}
+}
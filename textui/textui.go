@@ -0,0 +1,229 @@
+// Package textui is a terminal client for the game, for playing without a
+// window.
+package textui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/prizelobby/pyramid-rummy/core"
+)
+
+const CELL_WIDTH = 5
+
+// slotCells places each slot on the grid of the Pyramid diagram as a row and a
+// column
+var slotCells = [10][2]int{
+	{0, 3}, // 0
+	{2, 1}, // 1
+	{2, 5}, // 2
+	{5, 0}, // 3
+	{5, 3}, // 4
+	{5, 6}, // 5
+	{1, 3}, // 6
+	{4, 2}, // 7
+	{4, 4}, // 8
+	{3, 3}, // 9
+}
+
+const GRID_ROWS = 6
+const GRID_COLS = 7
+
+func cell(p *core.Pyramid, i int) string {
+	if c := p.Cards[i]; c != nil {
+		return c.String()
+	}
+	if p.CanPlace(i) {
+		return "[" + strconv.Itoa(i) + "]"
+	}
+	return "."
+}
+
+// RenderPyramid draws the pyramid as lines of text. Placed cards show their
+// value and color, slots that can be filled show their number in brackets and
+// slots that are still blocked show a dot.
+func RenderPyramid(p *core.Pyramid) []string {
+	var grid [GRID_ROWS][GRID_COLS]string
+	for i, rc := range slotCells {
+		grid[rc[0]][rc[1]] = cell(p, i)
+	}
+	lines := make([]string, GRID_ROWS)
+	for r, row := range grid {
+		var sb strings.Builder
+		for _, s := range row {
+			pad := CELL_WIDTH - len(s)
+			sb.WriteString(strings.Repeat(" ", pad/2) + s + strings.Repeat(" ", pad-pad/2))
+		}
+		lines[r] = strings.TrimRight(sb.String(), " ")
+	}
+	return lines
+}
+
+// RenderGame draws both pyramids side by side with the cards in play and the
+// scores.
+func RenderGame(g *core.Game) string {
+	var sb strings.Builder
+	if g.Solo {
+		fmt.Fprintf(&sb, "Score: %d   Target: %d\n", g.Pyramid1.Score(), core.SOLO_TARGET)
+		for _, line := range RenderPyramid(g.Pyramid1) {
+			sb.WriteString(line + "\n")
+		}
+		renderPiles(&sb, g)
+		if g.State == core.IN_PROGRESS {
+			fmt.Fprintf(&sb, "Turn %d of %d\n", g.Turn+1, g.Turns())
+		}
+		return sb.String()
+	}
+	width := CELL_WIDTH * GRID_COLS
+	fmt.Fprintf(&sb, "%-*s    %s\n", width, fmt.Sprintf("Player 1: %d", g.Pyramid1.Score()), fmt.Sprintf("Player 2: %d", g.Pyramid2.Score()))
+	left, right := RenderPyramid(g.Pyramid1), RenderPyramid(g.Pyramid2)
+	for i := range left {
+		fmt.Fprintf(&sb, "%-*s    %s\n", width, left[i], right[i])
+	}
+	renderPiles(&sb, g)
+	if g.State == core.IN_PROGRESS {
+		fmt.Fprintf(&sb, "Turn %d, player %d to move\n", g.Turn+1, g.CurrentPlayer()+1)
+	}
+	return sb.String()
+}
+
+func renderPiles(sb *strings.Builder, g *core.Game) {
+	sb.WriteString("\n")
+	if top := g.TopDiscard(); top != nil {
+		fmt.Fprintf(sb, "Revealed: %s (%d in the stack)\n", top, len(g.Discards))
+	} else {
+		sb.WriteString("Revealed: none\n")
+	}
+	fmt.Fprintf(sb, "Deck: %d   Draws left: %d\n", len(g.Deck), g.DrawsLeft)
+}
+
+// DescribeMove says what m did, with the players called by their seats.
+func DescribeMove(m core.Move) string {
+	return m.Describe([2]string{core.DefaultPlayerName(0), core.DefaultPlayerName(1)})
+}
+
+func Result(g *core.Game) string {
+	switch g.State {
+	case core.P1_WIN:
+		return "Player 1 wins"
+	case core.P2_WIN:
+		return "Player 2 wins"
+	case core.DRAW:
+		return "Draw"
+	case core.FINISHED:
+		return "Finished"
+	}
+	return "Game not finished"
+}
+
+const HELP = `Commands:
+  draw, d       draw a card from the deck
+  play N, p N   place the revealed card in slot N
+  hint          show the value of each legal action
+  board, b      show the board again
+  help, ?       show this help
+  quit, q       leave the game`
+
+var ErrQuit = errors.New("quit")
+
+// ParseCommand reads a typed command. Commands that are not moves return an
+// event with a negative target and the command name.
+func ParseCommand(line string) (core.AgentEvent, string, error) {
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) == 0 {
+		return core.AgentEvent{}, "", errors.New("type a command, or help")
+	}
+	switch fields[0] {
+	case "draw", "d":
+		return core.AgentEvent{EventType: core.DRAW_CARDS}, "draw", nil
+	case "play", "p":
+		if len(fields) < 2 {
+			return core.AgentEvent{}, "", errors.New("play needs a slot number, like play 7")
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return core.AgentEvent{}, "", errors.New("not a slot number: " + fields[1])
+		}
+		return core.AgentEvent{EventType: core.PLAY_CARD, Target: n}, "play", nil
+	case "hint", "board", "help", "quit":
+		return core.AgentEvent{Target: -1}, fields[0], nil
+	case "b":
+		return core.AgentEvent{Target: -1}, "board", nil
+	case "?":
+		return core.AgentEvent{Target: -1}, "help", nil
+	case "q", "exit":
+		return core.AgentEvent{Target: -1}, "quit", nil
+	}
+	return core.AgentEvent{}, "", errors.New("unknown command " + fields[0] + ", type help")
+}
+
+// Play runs g to completion, reading moves for the human seats from in. Seats
+// without an agent are played by a person. Hints are scored with model.
+func Play(in io.Reader, out io.Writer, g *core.Game, agents [2]core.GameAgent, model *core.LinearModel) error {
+	if model == nil {
+		model = core.DefaultModel
+	}
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintln(out, "Type help for the list of commands.")
+	showBoard := true
+	for g.State == core.IN_PROGRESS {
+		player := g.CurrentPlayer()
+		if a := agents[player]; a != nil {
+			a.SetVisibleCard(g.TopDiscard())
+			if _, err := core.ApplyAndNotify(g, agents, a.GenerateMove()); err != nil {
+				return err
+			}
+			fmt.Fprintln(out, DescribeMove(g.History[len(g.History)-1]))
+			showBoard = true
+			continue
+		}
+
+		if showBoard {
+			fmt.Fprint(out, "\n"+RenderGame(g))
+			showBoard = false
+		}
+		fmt.Fprintf(out, "player %d> ", player+1)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return err
+			}
+			return ErrQuit
+		}
+		e, command, err := ParseCommand(scanner.Text())
+		if err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+		switch command {
+		case "draw", "play":
+			if _, err := core.ApplyAndNotify(g, agents, e); err != nil {
+				fmt.Fprintln(out, "You can't "+e.String()+" now.")
+				continue
+			}
+			fmt.Fprintln(out, DescribeMove(g.History[len(g.History)-1]))
+			showBoard = true
+		case "hint":
+			for _, v := range model.ActionValues(core.StateFromGame(g)) {
+				fmt.Fprintf(out, "  %-8s %+.1f\n", v.Event.String(), v.Value)
+			}
+		case "board":
+			showBoard = true
+		case "help":
+			fmt.Fprintln(out, HELP)
+		case "quit":
+			return ErrQuit
+		}
+	}
+
+	fmt.Fprint(out, "\n"+RenderGame(g))
+	if g.Solo {
+		fmt.Fprintf(out, "%s with %d points\n", Result(g), g.Pyramid1.Score())
+	} else {
+		fmt.Fprintf(out, "%s, %d to %d\n", Result(g), g.Pyramid1.Score(), g.Pyramid2.Score())
+	}
+	return nil
+}
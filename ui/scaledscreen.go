@@ -64,6 +64,16 @@ func (s *ScaledScreen) DrawCircle(cx, cy, r float64, color color.Color) {
 	vector.DrawFilledCircle(s.Screen, xx, yy, rr, color, false)
 }
 
+func (s *ScaledScreen) DrawLine(x1, y1, x2, y2, strokeWidth float64, color color.Color) {
+	xx1 := float32(x1 * s.scaleFactor)
+	yy1 := float32(y1 * s.scaleFactor)
+	xx2 := float32(x2 * s.scaleFactor)
+	yy2 := float32(y2 * s.scaleFactor)
+	sw := float32(strokeWidth * s.scaleFactor)
+
+	vector.StrokeLine(s.Screen, xx1, yy1, xx2, yy2, sw, color, true)
+}
+
 func (s *ScaledScreen) DrawRectShader(w, h int, shader *ebiten.Shader, opts *ebiten.DrawRectShaderOptions) {
 	ww := int(float64(w) * s.scaleFactor)
 	hh := int(float64(h) * s.scaleFactor)
@@ -0,0 +1,7 @@
+//go:build !js
+
+package main
+
+func gameCodeFromURL() string {
+	return ""
+}
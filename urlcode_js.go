@@ -0,0 +1,20 @@
+//go:build js
+
+package main
+
+import "syscall/js"
+
+// gameCodeFromURL reads the game query parameter of the page, so a link like
+// index.html?game=CODE opens that game.
+func gameCodeFromURL() string {
+	search := js.Global().Get("location").Get("search")
+	if search.IsUndefined() {
+		return ""
+	}
+	params := js.Global().Get("URLSearchParams").New(search)
+	code := params.Call("get", "game")
+	if code.IsNull() {
+		return ""
+	}
+	return code.String()
+}
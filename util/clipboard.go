@@ -0,0 +1,19 @@
+//go:build !js
+
+package util
+
+import "github.com/atotto/clipboard"
+
+// ReadClipboard passes the text on the clipboard to done, or an empty string
+// if it can't be read.
+func ReadClipboard(done func(string)) {
+	text, err := clipboard.ReadAll()
+	if err != nil {
+		text = ""
+	}
+	done(text)
+}
+
+func WriteClipboard(text string) error {
+	return clipboard.WriteAll(text)
+}
@@ -0,0 +1,50 @@
+//go:build js
+
+package util
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+func browserClipboard() js.Value {
+	c := js.Global().Get("navigator").Get("clipboard")
+	if c.IsUndefined() || c.IsNull() {
+		return js.Undefined()
+	}
+	return c
+}
+
+// ReadClipboard passes the text on the clipboard to done once the browser has
+// read it, or an empty string if it can't be read. The browser only reads it
+// in answer to a key press or click, and may ask the player first.
+func ReadClipboard(done func(string)) {
+	c := browserClipboard()
+	if c.IsUndefined() {
+		done("")
+		return
+	}
+	var then, catch js.Func
+	then = js.FuncOf(func(this js.Value, args []js.Value) any {
+		done(args[0].String())
+		then.Release()
+		catch.Release()
+		return nil
+	})
+	catch = js.FuncOf(func(this js.Value, args []js.Value) any {
+		done("")
+		then.Release()
+		catch.Release()
+		return nil
+	})
+	c.Call("readText").Call("then", then).Call("catch", catch)
+}
+
+func WriteClipboard(text string) error {
+	c := browserClipboard()
+	if c.IsUndefined() {
+		return errors.New("the clipboard is not available")
+	}
+	c.Call("writeText", text)
+	return nil
+}